@@ -0,0 +1,90 @@
+package bloomfilter
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// adaptiveOrder 根据统计结果调整 Contains 检查哈希位置的顺序
+// 每 every 次查询抽样一次：抽样的查询检查全部 k 个位置，记录每个位置为 0 的次数，
+// 然后把为 0 次数多的位置排到前面，使不存在的元素更早遇到 0 位而返回。
+// 只统计第一个 0 位会让排在最前的位置独占所有计数，顺序永远不会改变，因此抽样时不短路。
+// 计数与顺序都以原子操作读写，可以与其他 Contains 并发执行。检查顺序不影响结果，只影响速度
+type adaptiveOrder struct {
+	order   atomic.Pointer[[]int] // 当前的检查顺序，重新排序时整体替换
+	zeros   []atomic.Uint64       // 抽样查询中每个哈希位置为 0 的次数
+	every   uint64                // 每多少次查询抽样一次
+	lookups atomic.Uint64         // 查询次数
+}
+
+func newAdaptiveOrder(hashCount, every int) *adaptiveOrder {
+	order := make([]int, hashCount)
+	for i := range order {
+		order[i] = i
+	}
+	a := &adaptiveOrder{
+		zeros: make([]atomic.Uint64, hashCount),
+		every: uint64(every),
+	}
+	a.order.Store(&order)
+	return a
+}
+
+// EnableAdaptiveOrder 开启自适应检查顺序，每 every 次查询抽样一次并按统计结果重新排序
+// 适用于大部分查询都是不存在元素的场景（如缓存穿透攻击）。
+// 统计以原子操作更新，开启后 Contains 仍可在 SyncBloomFilter 的读锁下或并发模式中同时调用，
+// 但 EnableAdaptiveOrder、DisableAdaptiveOrder 本身不能与查询并发调用。
+// 双重哈希下各位置为 0 的概率相同，位置之间没有密度差异时收益有限，
+// 是否开启请以 BenchmarkContainsNegativeAdaptive 与 BenchmarkContainsNegative 的对比为准
+func (bf *BloomFilter) EnableAdaptiveOrder(every int) {
+	if every < 1 {
+		every = 1
+	}
+	bf.adaptive = newAdaptiveOrder(bf.hashCount, every)
+}
+
+// DisableAdaptiveOrder 关闭自适应检查顺序，恢复固定顺序
+func (bf *BloomFilter) DisableAdaptiveOrder() {
+	bf.adaptive = nil
+}
+
+// contains 按当前顺序检查各哈希位置，每 every 次查询改为抽样
+func (a *adaptiveOrder) contains(bf *BloomFilter, h1, h2 uint64) bool {
+	if a.lookups.Add(1)%a.every == 0 {
+		return a.sample(bf, h1, h2)
+	}
+
+	for _, i := range *a.order.Load() {
+		if !bf.getBit(bf.position(h1, h2, i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// sample 检查全部位置并记录每个为 0 的位置，然后重新排序
+func (a *adaptiveOrder) sample(bf *BloomFilter, h1, h2 uint64) bool {
+	found := true
+	for i := range a.zeros {
+		if !bf.getBit(bf.position(h1, h2, i)) {
+			a.zeros[i].Add(1)
+			found = false
+		}
+	}
+	a.reorder()
+	return found
+}
+
+// reorder 按为 0 的次数从多到少重新排序
+// 并发的抽样可能同时重新排序，每次替换的都是完整的排列，后替换的生效
+func (a *adaptiveOrder) reorder() {
+	counts := make([]uint64, len(a.zeros))
+	for i := range a.zeros {
+		counts[i] = a.zeros[i].Load()
+	}
+	order := append([]int(nil), *a.order.Load()...)
+	sort.SliceStable(order, func(x, y int) bool {
+		return counts[order[x]] > counts[order[y]]
+	})
+	a.order.Store(&order)
+}
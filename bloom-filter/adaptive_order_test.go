@@ -0,0 +1,101 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestAdaptiveOrder 测试自适应检查顺序不影响查询结果
+func TestAdaptiveOrder(t *testing.T) {
+	fixed := NewBloomFilter(10000, 0.01)
+	adaptive := NewBloomFilter(10000, 0.01)
+	adaptive.EnableAdaptiveOrder(100)
+
+	for i := 0; i < 5000; i++ {
+		key := []byte(fmt.Sprintf("user:%d", i))
+		fixed.Add(key)
+		adaptive.Add(key)
+	}
+
+	for i := 0; i < 20000; i++ {
+		key := []byte(fmt.Sprintf("attack:%d", i))
+		if fixed.Contains(key) != adaptive.Contains(key) {
+			t.Fatalf("%s: 自适应顺序的结果应与固定顺序一致", key)
+		}
+	}
+	for i := 0; i < 5000; i++ {
+		if !adaptive.Contains([]byte(fmt.Sprintf("user:%d", i))) {
+			t.Fatalf("应该包含 user:%d", i)
+		}
+	}
+}
+
+// TestAdaptiveOrderDensity 测试最常为 0 的位置被排到最前面，即使它原本排在最后
+func TestAdaptiveOrderDensity(t *testing.T) {
+	bf := NewBloomFilter(1000, 0.01)
+	k := bf.hashCount
+	// 除第 k-1 个哈希位置外全部置 1：h2 = 1 时第 i 个位置为 h1 + i
+	for pos := 0; pos < bf.size; pos++ {
+		if pos%k != k-1 {
+			bf.setBit(pos)
+		}
+	}
+	bf.EnableAdaptiveOrder(1)
+
+	for j := 0; j < 100; j++ {
+		if bf.ContainsHash(uint64(j*k), 1) {
+			t.Fatal("第 k-1 个位置为 0, 应该判定不存在")
+		}
+	}
+	if first := (*bf.adaptive.order.Load())[0]; first != k-1 {
+		t.Errorf("最常为 0 的位置 %d 应该排在最前面, 实际: %d", k-1, first)
+	}
+}
+
+// TestAdaptiveOrderConcurrent 测试开启自适应顺序后读锁下与并发模式下的查询（配合 -race 运行）
+func TestAdaptiveOrderConcurrent(t *testing.T) {
+	s := NewSyncBloomFilter(10000, 0.01)
+	s.EnableAdaptiveOrder(10)
+	cf := NewConcurrentBloomFilter(10000, 0.01)
+	cf.EnableAdaptiveOrder(10)
+
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := []byte(fmt.Sprintf("key-%d-%d", r, i))
+				s.Contains(key)
+				cf.Add(key)
+				cf.Contains(key)
+			}
+		}(r)
+	}
+	wg.Wait()
+
+	for i := 0; i < 2000; i++ {
+		if !cf.Contains([]byte(fmt.Sprintf("key-0-%d", i))) {
+			t.Fatalf("key-0-%d 添加后应该存在", i)
+		}
+	}
+}
+
+// BenchmarkContainsNegativeAdaptive 开启自适应顺序后不存在元素的查询性能，与 BenchmarkContainsNegative 对比
+func BenchmarkContainsNegativeAdaptive(b *testing.B) {
+	bf := NewBloomFilter(100000, 0.01)
+	for i := 0; i < 100000; i++ {
+		bf.Add([]byte(fmt.Sprintf("user:%d", i)))
+	}
+	bf.EnableAdaptiveOrder(1000)
+	keys := make([][]byte, 10000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("attack:%d", i))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.Contains(keys[i%len(keys)])
+	}
+}
@@ -0,0 +1,100 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+// BloomFilter32 使用 32 位哈希和 uint32 位图的布隆过滤器
+// 位图大小必须能用 32 位表示 (m <= math.MaxUint32)，
+// 建议在 m 不超过 1<<20 位（128 KiB，约 p=0.01 时 10 万个元素）时使用。
+// 位图占用与 BloomFilter 基本相同，收益主要来自更便宜的 32 位哈希和取模；
+// m 较大时 32 位哈希的分布不如 64 位均匀，应使用 BloomFilter
+type BloomFilter32 struct {
+	bits      []uint32 // 位图，每个 uint32 存放 32 位
+	size      uint32   // 位图大小
+	hashCount int      // 哈希函数数量
+}
+
+// NewBloomFilter32 创建一个 32 位的布隆过滤器
+// n: 预计插入的元素数量
+// p: 期望的误判率 (0 < p < 1)
+// 计算出的位图大小超过 math.MaxUint32 时 panic
+func NewBloomFilter32(n int, p float64) *BloomFilter32 {
+	m := optimalSize(n, p)
+	if uint64(m) > math.MaxUint32 {
+		panic(fmt.Sprintf("bloomfilter: size %d exceeds 32-bit range", m))
+	}
+
+	return &BloomFilter32{
+		bits:      make([]uint32, (m+31)/32),
+		size:      uint32(m),
+		hashCount: optimalHashCount(n, m),
+	}
+}
+
+// hashPair32 计算元素的两个 32 位基础哈希值
+func hashPair32(data []byte) (h1, h2 uint32) {
+	h := fnv.New32a()
+	h.Write(data)
+	sum := h.Sum32()
+
+	h1 = mix32(sum)
+	h2 = mix32(sum ^ 0x9e3779b9)
+	return h1, h2
+}
+
+// mix32 murmur3 的 32 位混淆函数
+func mix32(x uint32) uint32 {
+	x ^= x >> 16
+	x *= 0x85ebca6b
+	x ^= x >> 13
+	x *= 0xc2b2ae35
+	x ^= x >> 16
+	return x
+}
+
+// position 计算第 i 个哈希函数对应的位
+func (bf *BloomFilter32) position(h1, h2 uint32, i int) uint32 {
+	return (h1 + uint32(i)*h2) % bf.size
+}
+
+// Add 添加元素到布隆过滤器
+func (bf *BloomFilter32) Add(data []byte) {
+	h1, h2 := hashPair32(data)
+	for i := 0; i < bf.hashCount; i++ {
+		pos := bf.position(h1, h2, i)
+		bf.bits[pos/32] |= 1 << (pos % 32)
+	}
+}
+
+// Contains 检查元素是否可能存在
+// 返回 true 表示可能存在, false 表示一定不存在
+func (bf *BloomFilter32) Contains(data []byte) bool {
+	h1, h2 := hashPair32(data)
+	for i := 0; i < bf.hashCount; i++ {
+		pos := bf.position(h1, h2, i)
+		if bf.bits[pos/32]&(1<<(pos%32)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Clear 清空布隆过滤器
+func (bf *BloomFilter32) Clear() {
+	for i := range bf.bits {
+		bf.bits[i] = 0
+	}
+}
+
+// Size 返回布隆过滤器的大小
+func (bf *BloomFilter32) Size() int {
+	return int(bf.size)
+}
+
+// HashCount 返回哈希函数数量
+func (bf *BloomFilter32) HashCount() int {
+	return bf.hashCount
+}
@@ -0,0 +1,72 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"testing"
+	"unsafe"
+)
+
+// TestBloomFilter32 测试 32 位过滤器的基本行为和误判率
+func TestBloomFilter32(t *testing.T) {
+	n := 1000
+	p := 0.01
+	bf := NewBloomFilter32(n, p)
+
+	for i := 0; i < n; i++ {
+		bf.Add([]byte(fmt.Sprintf("item%d", i)))
+	}
+	for i := 0; i < n; i++ {
+		if !bf.Contains([]byte(fmt.Sprintf("item%d", i))) {
+			t.Fatalf("item%d 应该存在", i)
+		}
+	}
+
+	falsePositive := 0
+	tests := 10000
+	for i := 0; i < tests; i++ {
+		if bf.Contains([]byte(fmt.Sprintf("nonexistent%d", i))) {
+			falsePositive++
+		}
+	}
+	actualRate := float64(falsePositive) / float64(tests)
+	t.Logf("期望误判率: %.4f, 实际误判率: %.4f", p, actualRate)
+	if actualRate > p*3 {
+		t.Errorf("实际误判率 %.4f 远高于期望值 %.4f", actualRate, p)
+	}
+
+	bf.Clear()
+	if bf.Contains([]byte("item0")) {
+		t.Error("清空后元素不应该存在")
+	}
+}
+
+// BenchmarkContains32 比较小过滤器下 32 位与 64 位版本的内存和查询速度
+func BenchmarkContains32(b *testing.B) {
+	n := 1000
+	p := 0.01
+	key := []byte("item500")
+
+	b.Run("64bit", func(b *testing.B) {
+		bf := NewBloomFilter(n, p)
+		for i := 0; i < n; i++ {
+			bf.Add([]byte(fmt.Sprintf("item%d", i)))
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			bf.Contains(key)
+		}
+		b.ReportMetric(float64(len(bf.bits)*int(unsafe.Sizeof(bf.bits[0]))), "bitmap-bytes")
+	})
+
+	b.Run("32bit", func(b *testing.B) {
+		bf := NewBloomFilter32(n, p)
+		for i := 0; i < n; i++ {
+			bf.Add([]byte(fmt.Sprintf("item%d", i)))
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			bf.Contains(key)
+		}
+		b.ReportMetric(float64(len(bf.bits)*int(unsafe.Sizeof(bf.bits[0]))), "bitmap-bytes")
+	})
+}
@@ -1,18 +1,35 @@
 package bloomfilter
 
 import (
-	"hash"
+	"fmt"
 	"hash/fnv"
 	"math"
+	"sync/atomic"
+	"unsafe"
 )
 
 // BloomFilter 布隆过滤器结构
 type BloomFilter struct {
-	bitSet    []bool      // 位图
+	bits      []uint64    // 位图，每个 uint64 存放 64 位
 	size      int         // 位图大小
-	hashFuncs []hash.Hash64 // 哈希函数列表
+	hashCount int         // 哈希函数数量
+
+	saturationThreshold float64 // TryAdd 判定饱和的填充率阈值, 0 表示使用默认值
+
+	adaptive *adaptiveOrder // 自适应检查顺序, nil 表示按固定顺序检查
+
+	concurrent bool // 是否以原子操作读写位图，见 NewConcurrentBloomFilter
+
+	changeLog *changeLog // 记录添加的元素用于复制, nil 表示不记录
+
+	seedH1, seedH2 uint64 // 由种子派生的哈希扰动值, 未设置种子时为 0
+
+	hasher Hasher // 自定义哈希函数, nil 表示使用 HashPair
 }
 
+// Hasher 计算元素的两个基础哈希值，签名与 HashPair 相同
+type Hasher func(data []byte) (h1, h2 uint64)
+
 // NewBloomFilter 创建一个新的布隆过滤器
 // n: 预计插入的元素数量
 // p: 期望的误判率 (0 < p < 1)
@@ -24,85 +41,219 @@ func NewBloomFilter(n int, p float64) *BloomFilter {
 	k := optimalHashCount(n, m)
 	
 	// 创建位图
-	bitSet := make([]bool, m)
-	
-	// 创建哈希函数
-	hashFuncs := make([]hash.Hash64, k)
-	for i := 0; i < k; i++ {
-		hashFuncs[i] = fnv.New64a()
-	}
+	bits := make([]uint64, wordCount(m))
 	
 	return &BloomFilter{
-		bitSet:    bitSet,
+		bits:      bits,
 		size:      m,
-		hashFuncs: hashFuncs,
+		hashCount: k,
+	}
+}
+
+// NewBloomFilterK 使用指定的哈希函数数量创建布隆过滤器
+// 位图大小仍按 n 和 p 计算，k 由调用方指定 (k >= 1)
+// 比最优值更小的 k 每次操作计算的位置更少、速度更快，但实际误判率会高于 p
+func NewBloomFilterK(n int, p float64, k int) (*BloomFilter, error) {
+	if k < 1 {
+		return nil, fmt.Errorf("invalid hash count: %d", k)
 	}
+
+	bf := NewBloomFilter(n, p)
+	bf.hashCount = k
+	return bf, nil
+}
+
+// NewBloomFilterSeeded 使用指定种子创建布隆过滤器
+// 种子参与位置的派生：相同的种子和输入总是置相同的位，不同的种子得到不同的位分布。
+// 可以在 CI 中固定种子得到可复现的误判率，或换一个种子排查与特定输入相关的问题。
+// 种子不包含在序列化格式中，反序列化时需要使用以相同种子创建的过滤器
+func NewBloomFilterSeeded(n int, p float64, seed uint64) *BloomFilter {
+	bf := NewBloomFilter(n, p)
+	bf.seedH1 = mix64(seed)
+	bf.seedH2 = mix64(seed ^ 0x9e3779b97f4a7c15)
+	return bf
 }
 
-// optimalSize 计算最优的位图大小
+// NewBloomFilterWithHasher 使用自定义哈希函数创建布隆过滤器
+// hasher 需要对输入分布均匀，否则置位集中在部分区域、误判率升高，可用 HashUniformity 检查。
+// 哈希函数不包含在序列化格式中，Equal 也不比较哈希函数
+func NewBloomFilterWithHasher(n int, p float64, hasher Hasher) *BloomFilter {
+	bf := NewBloomFilter(n, p)
+	bf.hasher = hasher
+	return bf
+}
+
+// optimalSize 计算最优的位图大小，至少为 1
+// p 接近 1 或 n 为 0 时公式结果可能为 0，位图为空会在取模时 panic
 func optimalSize(n int, p float64) int {
 	m := -float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)
-	return int(math.Ceil(m))
+	return max(int(math.Ceil(m)), 1)
 }
 
-// optimalHashCount 计算最优的哈希函数数量
+// optimalHashCount 计算最优的哈希函数数量，至少为 1
+// k 为 0 时 Add 和 Contains 不检查任何位，Contains 总是返回 true
 func optimalHashCount(n, m int) int {
+	if n < 1 {
+		n = 1
+	}
 	k := float64(m) / float64(n) * math.Ln2
-	return int(math.Ceil(k))
+	return max(int(math.Ceil(k)), 1)
+}
+
+// wordCount 计算存放 m 位所需的 uint64 数量
+func wordCount(m int) int {
+	return (m + 63) / 64
+}
+
+// setBit 将第 pos 位置为 1
+func (bf *BloomFilter) setBit(pos int) {
+	if bf.concurrent {
+		atomicOr(&bf.bits[pos/64], 1<<(uint(pos)%64))
+		return
+	}
+	bf.bits[pos/64] |= 1 << (uint(pos) % 64)
+}
+
+// getBit 判断第 pos 位是否为 1
+func (bf *BloomFilter) getBit(pos int) bool {
+	if bf.concurrent {
+		return atomic.LoadUint64(&bf.bits[pos/64])&(1<<(uint(pos)%64)) != 0
+	}
+	return bf.bits[pos/64]&(1<<(uint(pos)%64)) != 0
+}
+
+// HashPair 计算元素的两个基础哈希值
+// 过滤器使用双重哈希 h1 + i*h2 派生出 k 个位置，调用方可预先计算后复用
+// nil 与空切片的哈希值相同，视为同一个元素；k 个位置全部由这两个哈希值派生，
+// 不逐个拼接盐值，因此空元素与其他元素一样分布在 k 个不同的位置上
+func HashPair(data []byte) (h1, h2 uint64) {
+	h := fnv.New64a()
+	h.Write(data)
+	sum := h.Sum64()
+
+	// FNV 对相近的输入区分度不足，再经过一次混淆得到两个相互独立的哈希值
+	h1 = mix64(sum)
+	h2 = mix64(sum ^ 0x9e3779b97f4a7c15)
+	return h1, h2
+}
+
+// mix64 splitmix64 的混淆函数
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// hashPair 使用过滤器的哈希函数计算元素的两个基础哈希值
+func (bf *BloomFilter) hashPair(data []byte) (h1, h2 uint64) {
+	if bf.hasher != nil {
+		return bf.hasher(data)
+	}
+	return HashPair(data)
+}
+
+// position 计算第 i 个哈希函数对应的位
+func (bf *BloomFilter) position(h1, h2 uint64, i int) int {
+	h1 ^= bf.seedH1
+	h2 ^= bf.seedH2
+	return int((h1 + uint64(i)*h2) % uint64(bf.size))
 }
 
 // Add 添加元素到布隆过滤器
+// Add(nil) 与 Add([]byte("")) 添加的是同一个元素
 func (bf *BloomFilter) Add(data []byte) {
-	for i, h := range bf.hashFuncs {
-		// 重置哈希函数
-		h.Reset()
-		
-		// 写入数据
-		h.Write(data)
-		
-		// 写入索引以区分不同的哈希函数
-		h.Write([]byte{byte(i)})
-		
-		// 获取哈希值并计算位置
-		hashValue := h.Sum64()
-		position := int(hashValue % uint64(bf.size))
-		
-		// 设置位
-		bf.bitSet[position] = true
+	bf.AddHash(bf.hashPair(data))
+	if bf.changeLog != nil {
+		bf.changeLog.append(data)
 	}
 }
 
+// AddAll 批量添加元素
+func (bf *BloomFilter) AddAll(keys [][]byte) {
+	for _, key := range keys {
+		bf.Add(key)
+	}
+}
+
+// ContainsAll 批量检查元素, 第 i 个结果对应 items[i]
+func (bf *BloomFilter) ContainsAll(items [][]byte) []bool {
+	results := make([]bool, len(items))
+	for i, item := range items {
+		results[i] = bf.Contains(item)
+	}
+	return results
+}
+
 // Contains 检查元素是否可能存在
 // 返回 true 表示可能存在, false 表示一定不存在
 func (bf *BloomFilter) Contains(data []byte) bool {
-	for i, h := range bf.hashFuncs {
-		// 重置哈希函数
-		h.Reset()
-		
-		// 写入数据
-		h.Write(data)
-		
-		// 写入索引以区分不同的哈希函数
-		h.Write([]byte{byte(i)})
-		
-		// 获取哈希值并计算位置
-		hashValue := h.Sum64()
-		position := int(hashValue % uint64(bf.size))
-		
-		// 如果任意一位为 false, 则元素一定不存在
-		if !bf.bitSet[position] {
+	return bf.ContainsHash(bf.hashPair(data))
+}
+
+// AddHash 使用预先计算的哈希值（见 HashPair）添加元素，跳过内部哈希计算
+func (bf *BloomFilter) AddHash(h1, h2 uint64) {
+	for i := 0; i < bf.hashCount; i++ {
+		bf.setBit(bf.position(h1, h2, i))
+	}
+}
+
+// AddWithResult 添加元素并返回本次置位的 k 个位置
+// 位置可直接通过 SetBitsAt 应用到相同大小的副本上而无需重新计算哈希，
+// 用于跨节点复制过滤器的写日志，也便于排查哈希分布问题
+func (bf *BloomFilter) AddWithResult(data []byte) []int {
+	h1, h2 := bf.hashPair(data)
+	positions := make([]int, bf.hashCount)
+	for i := range positions {
+		positions[i] = bf.position(h1, h2, i)
+		bf.setBit(positions[i])
+	}
+	if bf.changeLog != nil {
+		bf.changeLog.append(data)
+	}
+	return positions
+}
+
+// SetBitsAt 将给定位置置为 1，用于应用 AddWithResult 的结果
+// 任意位置超出位图范围时返回错误且不修改过滤器
+func (bf *BloomFilter) SetBitsAt(positions []int) error {
+	for _, pos := range positions {
+		if pos < 0 || pos >= bf.size {
+			return fmt.Errorf("bit position %d out of range [0, %d)", pos, bf.size)
+		}
+	}
+	for _, pos := range positions {
+		bf.setBit(pos)
+	}
+	return nil
+}
+
+// ContainsHash 使用预先计算的哈希值（见 HashPair）检查元素是否可能存在
+func (bf *BloomFilter) ContainsHash(h1, h2 uint64) bool {
+	if bf.adaptive != nil {
+		return bf.adaptive.contains(bf, h1, h2)
+	}
+
+	for i := 0; i < bf.hashCount; i++ {
+		// 如果任意一位为 0, 则元素一定不存在
+		if !bf.getBit(bf.position(h1, h2, i)) {
 			return false
 		}
 	}
-	
-	// 所有位都为 true, 元素可能存在
+
+	// 所有位都为 1, 元素可能存在
 	return true
 }
 
 // Clear 清空布隆过滤器
+// 逐个字原子地置零。并发模式下可以与 Add、Contains 同时调用：
+// 并发的 Contains 可能看到部分清空的状态，只会多报（误判），不会漏报；
+// 与 Clear 同时进行的 Add 可能被部分清除，调用方需要自行决定是否重新添加
 func (bf *BloomFilter) Clear() {
-	for i := range bf.bitSet {
-		bf.bitSet[i] = false
+	for i := range bf.bits {
+		atomic.StoreUint64(&bf.bits[i], 0)
 	}
 }
 
@@ -113,5 +264,106 @@ func (bf *BloomFilter) Size() int {
 
 // HashCount 返回哈希函数数量
 func (bf *BloomFilter) HashCount() int {
-	return len(bf.hashFuncs)
+	return bf.hashCount
+}
+
+// Equal 判断两个布隆过滤器是否完全一致（大小、哈希函数数量和每一位）
+func (bf *BloomFilter) Equal(other *BloomFilter) bool {
+	if other == nil || bf.size != other.size || bf.hashCount != other.hashCount ||
+		bf.seedH1 != other.seedH1 || bf.seedH2 != other.seedH2 {
+		return false
+	}
+	for i := range bf.bits {
+		if bf.bits[i] != other.bits[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Fold 将位图折叠为原来的 1/factor，得到一个更小的过滤器
+// 位置 pos 映射到 pos mod (size/factor)，由于 h mod size mod (size/factor)
+// 等于 h mod (size/factor)，已添加的元素在折叠后的过滤器中仍然存在，不会产生假阴性，
+// 代价是误判率升高。size 必须能被 factor 整除
+func (bf *BloomFilter) Fold(factor int) (*BloomFilter, error) {
+	if factor < 1 || bf.size%factor != 0 {
+		return nil, fmt.Errorf("size %d is not divisible by factor %d", bf.size, factor)
+	}
+
+	newSize := bf.size / factor
+	folded := &BloomFilter{
+		bits:      make([]uint64, wordCount(newSize)),
+		size:      newSize,
+		hashCount: bf.hashCount,
+		seedH1:    bf.seedH1,
+		seedH2:    bf.seedH2,
+		hasher:    bf.hasher,
+	}
+	for pos := 0; pos < bf.size; pos++ {
+		if bf.getBit(pos) {
+			folded.setBit(pos % newSize)
+		}
+	}
+	return folded, nil
+}
+
+// Rebuild 按新的参数重建布隆过滤器
+// 布隆过滤器无法枚举自身元素，因此由调用方通过 reAddKeys 回调重新添加全部 key
+// 重建成功后原指针保持有效，调用方无需替换引用
+func (bf *BloomFilter) Rebuild(newN int, newP float64, reAddKeys func(add func([]byte))) error {
+	if newN <= 0 {
+		return fmt.Errorf("invalid expected elements: %d", newN)
+	}
+	if newP <= 0 || newP >= 1 {
+		return fmt.Errorf("invalid false positive rate: %v", newP)
+	}
+
+	// 在新的过滤器上重新添加元素，完成后再替换内部状态
+	// 新过滤器沿用原来的种子和哈希函数，否则按不同哈希置位的元素在重建后查不到
+	fresh := NewBloomFilter(newN, newP)
+	fresh.seedH1, fresh.seedH2 = bf.seedH1, bf.seedH2
+	fresh.hasher = bf.hasher
+	reAddKeys(fresh.Add)
+
+	bf.setState(fresh.bits, fresh.size, fresh.hashCount)
+	return nil
+}
+
+// Bits 返回位图底层数组的字节视图，用于零拷贝快照
+// 返回的切片与过滤器共享内存（按本机字节序排列），过滤器使用期间不得修改
+func (bf *BloomFilter) Bits() []byte {
+	if len(bf.bits) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&bf.bits[0])), len(bf.bits)*8)
+}
+
+// LoadBits 直接采用外部提供的位图缓冲区（如内存映射区域），不做拷贝
+// b 的长度必须与 size 对应的字数一致且按 8 字节对齐，之后 b 由过滤器持有
+func (bf *BloomFilter) LoadBits(b []byte, size, hashCount int) error {
+	if size <= 0 {
+		return fmt.Errorf("invalid size: %d", size)
+	}
+	if hashCount < 1 {
+		return fmt.Errorf("invalid hash count: %d", hashCount)
+	}
+	if len(b) != wordCount(size)*8 {
+		return fmt.Errorf("bits length %d does not match size %d", len(b), size)
+	}
+	if uintptr(unsafe.Pointer(&b[0]))%unsafe.Alignof(uint64(0)) != 0 {
+		return fmt.Errorf("bits buffer is not 8-byte aligned")
+	}
+
+	bf.setState(unsafe.Slice((*uint64)(unsafe.Pointer(&b[0])), len(b)/8), size, hashCount)
+	return nil
+}
+
+// setState 使用给定的位图和参数重置过滤器
+func (bf *BloomFilter) setState(bits []uint64, size, hashCount int) {
+	bf.bits = bits
+	bf.size = size
+	bf.hashCount = hashCount
+	if bf.adaptive != nil {
+		bf.adaptive = newAdaptiveOrder(hashCount, int(bf.adaptive.every))
+	}
 }
@@ -1,7 +1,9 @@
 package bloomfilter
 
 import (
+	"bytes"
 	"fmt"
+	"hash/fnv"
 	"math/rand"
 	"testing"
 )
@@ -106,6 +108,23 @@ func BenchmarkContains(b *testing.B) {
 	}
 }
 
+// BenchmarkContainsNegative 测试不存在元素（如缓存穿透攻击）的查找性能
+func BenchmarkContainsNegative(b *testing.B) {
+	bf := NewBloomFilter(100000, 0.01)
+	for i := 0; i < 100000; i++ {
+		bf.Add([]byte(fmt.Sprintf("user:%d", i)))
+	}
+	keys := make([][]byte, 10000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("attack:%d", i))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.Contains(keys[i%len(keys)])
+	}
+}
+
 // TestClear 测试清空功能
 func TestClear(t *testing.T) {
 	bf := NewBloomFilter(100, 0.01)
@@ -124,3 +143,367 @@ func TestClear(t *testing.T) {
 		t.Error("清空后布隆过滤器不应该包含任何元素")
 	}
 }
+
+// TestRebuild 测试按新参数重建
+func TestRebuild(t *testing.T) {
+	bf := NewBloomFilter(10, 0.1)
+	keys := make([][]byte, 1000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("item%d", i))
+		bf.Add(keys[i])
+	}
+	oldSize := bf.Size()
+
+	err := bf.Rebuild(len(keys), 0.01, func(add func([]byte)) {
+		for _, key := range keys {
+			add(key)
+		}
+	})
+	if err != nil {
+		t.Fatalf("重建失败: %v", err)
+	}
+
+	if bf.Size() <= oldSize {
+		t.Errorf("重建后位图应该变大, 原大小: %d, 新大小: %d", oldSize, bf.Size())
+	}
+	for _, key := range keys {
+		if !bf.Contains(key) {
+			t.Fatalf("重建后应该包含 %s", key)
+		}
+	}
+
+	// 参数非法时不修改原过滤器
+	size := bf.Size()
+	if err := bf.Rebuild(0, 0.01, func(add func([]byte)) {}); err == nil {
+		t.Error("非法参数应该返回错误")
+	}
+	if bf.Size() != size {
+		t.Error("重建失败时不应修改原过滤器")
+	}
+}
+
+// TestBitsRoundTrip 测试位图快照与恢复
+func TestBitsRoundTrip(t *testing.T) {
+	bf := NewBloomFilter(1000, 0.01)
+	for i := 0; i < 100; i++ {
+		bf.Add([]byte(fmt.Sprintf("item%d", i)))
+	}
+
+	// 模拟将位图拷贝到外部内存区域后恢复
+	snapshot := make([]byte, len(bf.Bits()))
+	copy(snapshot, bf.Bits())
+
+	restored := &BloomFilter{}
+	if err := restored.LoadBits(snapshot, bf.Size(), bf.HashCount()); err != nil {
+		t.Fatalf("恢复位图失败: %v", err)
+	}
+	if !bytes.Equal(restored.Bits(), bf.Bits()) {
+		t.Error("恢复后的位图应该与原位图一致")
+	}
+	for i := 0; i < 100; i++ {
+		if !restored.Contains([]byte(fmt.Sprintf("item%d", i))) {
+			t.Fatalf("恢复后应该包含 item%d", i)
+		}
+	}
+
+	// 恢复后的过滤器直接使用外部缓冲区
+	restored.Add([]byte("new-item"))
+	if bytes.Equal(snapshot, bf.Bits()) {
+		t.Error("恢复后的过滤器应该与外部缓冲区共享内存")
+	}
+
+	if err := restored.LoadBits(snapshot[:len(snapshot)-8], bf.Size(), bf.HashCount()); err == nil {
+		t.Error("长度不一致时应该返回错误")
+	}
+}
+
+// TestAddHash 测试使用预先计算的哈希值添加元素
+func TestAddHash(t *testing.T) {
+	a := NewBloomFilter(1000, 0.01)
+	b := NewBloomFilter(1000, 0.01)
+
+	for i := 0; i < 100; i++ {
+		key := []byte(fmt.Sprintf("item%d", i))
+		a.Add(key)
+		b.AddHash(HashPair(key))
+	}
+
+	if !a.Equal(b) {
+		t.Error("AddHash 与 Add 应该产生相同的过滤器")
+	}
+	if !b.ContainsHash(HashPair([]byte("item1"))) {
+		t.Error("ContainsHash 应该包含 item1")
+	}
+}
+
+// TestNewBloomFilterK 测试指定哈希函数数量
+func TestNewBloomFilterK(t *testing.T) {
+	bf, err := NewBloomFilterK(1000, 0.01, 3)
+	if err != nil {
+		t.Fatalf("创建失败: %v", err)
+	}
+	if bf.HashCount() != 3 {
+		t.Errorf("哈希函数数量应为 3, 实际: %d", bf.HashCount())
+	}
+	if bf.Size() != NewBloomFilter(1000, 0.01).Size() {
+		t.Error("位图大小应与按 p 计算的一致")
+	}
+
+	bf.Add([]byte("hello"))
+	if !bf.Contains([]byte("hello")) {
+		t.Error("应该包含 hello")
+	}
+
+	if _, err := NewBloomFilterK(1000, 0.01, 0); err == nil {
+		t.Error("k < 1 应该返回错误")
+	}
+}
+
+// BenchmarkHashCount 对比不同哈希函数数量的速度与误判率
+func BenchmarkHashCount(b *testing.B) {
+	n := 100000
+	optimal := NewBloomFilter(n, 0.01).HashCount()
+
+	for _, k := range []int{optimal, 4, 2} {
+		b.Run(fmt.Sprintf("k=%d", k), func(b *testing.B) {
+			bf, _ := NewBloomFilterK(n, 0.01, k)
+			for i := 0; i < n; i++ {
+				bf.Add([]byte(fmt.Sprintf("item%d", i)))
+			}
+
+			falsePositive := 0
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if bf.Contains([]byte(fmt.Sprintf("absent%d", i))) {
+					falsePositive++
+				}
+			}
+			b.ReportMetric(float64(falsePositive)/float64(b.N), "fp-rate")
+		})
+	}
+}
+
+// TestFold 测试折叠后不产生假阴性
+func TestFold(t *testing.T) {
+	bf := NewBloomFilter(1000, 0.01)
+	if bf.Size()%2 != 0 {
+		t.Fatalf("测试前提: 位图大小 %d 应为偶数", bf.Size())
+	}
+	for i := 0; i < 500; i++ {
+		bf.Add([]byte(fmt.Sprintf("item%d", i)))
+	}
+
+	folded, err := bf.Fold(2)
+	if err != nil {
+		t.Fatalf("折叠失败: %v", err)
+	}
+	if folded.Size() != bf.Size()/2 {
+		t.Errorf("折叠后大小应为 %d, 实际: %d", bf.Size()/2, folded.Size())
+	}
+	for i := 0; i < 500; i++ {
+		if !folded.Contains([]byte(fmt.Sprintf("item%d", i))) {
+			t.Fatalf("折叠后应该包含 item%d", i)
+		}
+	}
+
+	// 折叠后的过滤器与直接按小尺寸添加的结果一致
+	direct := &BloomFilter{bits: make([]uint64, wordCount(folded.Size())), size: folded.Size(), hashCount: bf.HashCount()}
+	for i := 0; i < 500; i++ {
+		direct.Add([]byte(fmt.Sprintf("item%d", i)))
+	}
+	if !folded.Equal(direct) {
+		t.Error("折叠结果应与直接按折叠后大小构建的过滤器一致")
+	}
+
+	if _, err := bf.Fold(bf.Size() + 1); err == nil {
+		t.Error("不能整除时应该返回错误")
+	}
+}
+
+// TestAddWithResult 测试通过置位位置复制过滤器
+func TestAddWithResult(t *testing.T) {
+	primary := NewBloomFilter(1000, 0.01)
+	replica := NewBloomFilter(1000, 0.01)
+
+	for i := 0; i < 500; i++ {
+		positions := primary.AddWithResult([]byte(fmt.Sprintf("item%d", i)))
+		if len(positions) != primary.HashCount() {
+			t.Fatalf("应返回 %d 个位置, 实际: %d", primary.HashCount(), len(positions))
+		}
+		if err := replica.SetBitsAt(positions); err != nil {
+			t.Fatalf("应用位置失败: %v", err)
+		}
+	}
+
+	if !primary.Equal(replica) {
+		t.Error("副本应与原过滤器一致")
+	}
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("item%d", i))
+		if primary.Contains(key) != replica.Contains(key) {
+			t.Errorf("%s 在两个过滤器中的结果不一致", key)
+		}
+	}
+
+	if err := replica.SetBitsAt([]int{0, replica.Size()}); err == nil {
+		t.Error("越界的位置应该返回错误")
+	}
+	if replica.getBit(0) != primary.getBit(0) {
+		t.Error("返回错误时不应修改过滤器")
+	}
+}
+
+// TestNewBloomFilterSeeded 测试相同种子得到相同的位图, 不同种子位分布不同
+func TestNewBloomFilterSeeded(t *testing.T) {
+	build := func(seed uint64) *BloomFilter {
+		bf := NewBloomFilterSeeded(1000, 0.01, seed)
+		for i := 0; i < 500; i++ {
+			bf.Add([]byte(fmt.Sprintf("item%d", i)))
+		}
+		return bf
+	}
+
+	a, b, c := build(42), build(42), build(43)
+	if !a.Equal(b) {
+		t.Error("相同种子和输入应得到相同的过滤器")
+	}
+	if a.Equal(c) || bytes.Equal(a.Bits(), c.Bits()) {
+		t.Error("不同种子应得到不同的位分布")
+	}
+	for i := 0; i < 500; i++ {
+		if !c.Contains([]byte(fmt.Sprintf("item%d", i))) {
+			t.Fatalf("item%d 应该存在", i)
+		}
+	}
+
+	factor := 2
+	for a.Size()%factor != 0 {
+		factor++
+	}
+	folded, err := a.Fold(factor)
+	if err != nil {
+		t.Fatalf("折叠失败: %v", err)
+	}
+	if !folded.Contains([]byte("item0")) {
+		t.Error("折叠后应保留种子, item0 应该存在")
+	}
+}
+
+// TestEmptyKey 测试 nil 与空切片视为同一个元素
+func TestEmptyKey(t *testing.T) {
+	bf := NewBloomFilter(1000, 0.01)
+	bf.Add(nil)
+	if !bf.Contains(nil) || !bf.Contains([]byte("")) {
+		t.Error("Add(nil) 后 nil 和空切片都应存在")
+	}
+
+	bf2 := NewBloomFilter(1000, 0.01)
+	bf2.Add([]byte(""))
+	if !bf2.Contains(nil) {
+		t.Error("Add([]byte(\"\")) 后 nil 应存在")
+	}
+	if !bf.Equal(bf2) {
+		t.Error("Add(nil) 与 Add([]byte(\"\")) 应置相同的位")
+	}
+
+	// 空元素同样派生出 k 个不同的位置, 不与单字节的 key 混淆
+	positions := NewBloomFilter(1000, 0.01).AddWithResult(nil)
+	distinct := make(map[int]bool)
+	for _, pos := range positions {
+		distinct[pos] = true
+	}
+	if len(distinct) != len(positions) {
+		t.Errorf("空元素应置 %d 个不同的位, 实际: %d", len(positions), len(distinct))
+	}
+	for _, key := range [][]byte{{0}, []byte(" "), []byte("0")} {
+		if bf.Contains(key) {
+			t.Errorf("只添加了空元素, %q 不应存在", key)
+		}
+	}
+
+	bf32 := NewBloomFilter32(1000, 0.01)
+	bf32.Add(nil)
+	if !bf32.Contains([]byte("")) {
+		t.Error("BloomFilter32: Add(nil) 后空切片应存在")
+	}
+}
+
+// TestLooseParameters 测试误判率很宽松或元素数量为 0 时, 位图和哈希函数数量至少为 1
+func TestLooseParameters(t *testing.T) {
+	for _, tc := range []struct {
+		n int
+		p float64
+	}{
+		{1000000, 0.99},
+		{1000000, 0.9999},
+		{1000, 1},
+		{0, 0.01},
+	} {
+		bf := NewBloomFilter(tc.n, tc.p)
+		if bf.Size() < 1 || bf.HashCount() < 1 {
+			t.Errorf("n=%d, p=%v: 期望位图与哈希函数数量至少为 1, 实际: %d, %d", tc.n, tc.p, bf.Size(), bf.HashCount())
+		}
+	}
+
+	// 只添加少量元素时, 宽松参数的过滤器仍能排除大部分不存在的元素
+	bf := NewBloomFilter(1000000, 0.99)
+	bf.Add([]byte("present"))
+	if !bf.Contains([]byte("present")) {
+		t.Error("已添加的元素应存在")
+	}
+	absent := 0
+	for i := 0; i < 1000; i++ {
+		if !bf.Contains([]byte(fmt.Sprintf("absent-%d", i))) {
+			absent++
+		}
+	}
+	if absent < 900 {
+		t.Errorf("Contains 应能排除不存在的元素, 1000 个中只排除了 %d 个", absent)
+	}
+}
+
+// rebuildAndCheck 向 bf 添加一批元素后重建, 检查重建后全部元素仍然存在
+func rebuildAndCheck(t *testing.T, bf *BloomFilter) {
+	t.Helper()
+	keys := make([][]byte, 100)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("rebuild-%d", i))
+		bf.Add(keys[i])
+	}
+	err := bf.Rebuild(1000, 0.01, func(add func([]byte)) {
+		for _, key := range keys {
+			add(key)
+		}
+	})
+	if err != nil {
+		t.Fatalf("重建失败: %v", err)
+	}
+	for _, key := range keys {
+		if !bf.Contains(key) {
+			t.Fatalf("重建后应该包含 %s", key)
+		}
+	}
+}
+
+// TestRebuildSeeded 测试带种子的过滤器重建后沿用原来的种子
+func TestRebuildSeeded(t *testing.T) {
+	bf := NewBloomFilterSeeded(100, 0.01, 42)
+	rebuildAndCheck(t, bf)
+
+	other := NewBloomFilterSeeded(1000, 0.01, 42)
+	other.Add([]byte("rebuild-0"))
+	if !bf.CompatibleWith(other) {
+		t.Error("重建后应与相同种子和参数的过滤器兼容")
+	}
+}
+
+// TestRebuildWithHasher 测试使用自定义哈希函数的过滤器重建后沿用原来的哈希函数
+func TestRebuildWithHasher(t *testing.T) {
+	hasher := func(data []byte) (uint64, uint64) {
+		h := fnv.New64a()
+		h.Write(data)
+		h1 := h.Sum64()
+		return h1, h1>>17 | 1
+	}
+	rebuildAndCheck(t, NewBloomFilterWithHasher(100, 0.01, hasher))
+}
@@ -0,0 +1,60 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// ErrNotSeekable 表示 BuildFromReader 的输入不支持 Seek，无法进行两遍读取
+var ErrNotSeekable = errors.New("bloom filter: reader must implement io.Seeker")
+
+// BuildFromReader 从按 lineSep 分隔的 key 流（如换行分隔的导出文件）构建布隆过滤器
+// 采用两遍读取：第一遍统计 key 数量以确定过滤器大小，Seek 回起点后第二遍逐个添加，
+// 任何时候只在内存中保留一个 key，适合预热数据量远大于内存的场景。
+// r 需要实现 io.Seeker（如 *os.File、*strings.Reader），否则返回 ErrNotSeekable。
+// key 不包含分隔符，空行被跳过；以 "\r\n" 结尾的文件需要调用方自行处理 '\r'
+func BuildFromReader(r io.Reader, lineSep byte, p float64) (*BloomFilter, error) {
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return nil, ErrNotSeekable
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	n := 0
+	if err := forEachKey(r, lineSep, func([]byte) { n++ }); err != nil {
+		return nil, err
+	}
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	bf := NewBloomFilter(max(n, 1), p)
+	if err := forEachKey(r, lineSep, bf.Add); err != nil {
+		return nil, err
+	}
+	return bf, nil
+}
+
+// forEachKey 对 r 中按 sep 分隔的每个非空 key 调用 fn，key 只在调用期间有效
+func forEachKey(r io.Reader, sep byte, fn func(key []byte)) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes(sep)
+		if len(line) > 0 && line[len(line)-1] == sep {
+			line = line[:len(line)-1]
+		}
+		if len(line) > 0 {
+			fn(line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
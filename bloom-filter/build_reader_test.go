@@ -0,0 +1,50 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestBuildFromReader 测试从换行分隔的 key 流构建过滤器
+func TestBuildFromReader(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&sb, "user:%d\n", i)
+	}
+	sb.WriteString("\nlast-without-newline")
+
+	bf, err := BuildFromReader(strings.NewReader(sb.String()), '\n', 0.01)
+	if err != nil {
+		t.Fatalf("构建失败: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		if key := fmt.Sprintf("user:%d", i); !bf.Contains([]byte(key)) {
+			t.Fatalf("%s 应该存在", key)
+		}
+	}
+	if !bf.Contains([]byte("last-without-newline")) {
+		t.Error("没有结尾分隔符的最后一个 key 应该存在")
+	}
+
+	// 过滤器按 key 数量(跳过空行)确定大小
+	if want := NewBloomFilter(1001, 0.01); bf.Size() != want.Size() {
+		t.Errorf("过滤器大小应为 %d, 实际: %d", want.Size(), bf.Size())
+	}
+
+	// 自定义分隔符
+	bf, err = BuildFromReader(strings.NewReader("a,b,c"), ',', 0.01)
+	if err != nil || !bf.Contains([]byte("b")) || bf.Contains([]byte("a,b")) {
+		t.Errorf("按 ',' 分隔构建的结果不正确, err: %v", err)
+	}
+}
+
+// TestBuildFromReaderNotSeekable 测试不支持 Seek 的输入返回 ErrNotSeekable
+func TestBuildFromReaderNotSeekable(t *testing.T) {
+	_, err := BuildFromReader(bytes.NewBufferString("a\nb\n"), '\n', 0.01)
+	if !errors.Is(err, ErrNotSeekable) {
+		t.Errorf("期望 ErrNotSeekable, 实际: %v", err)
+	}
+}
@@ -1,10 +1,18 @@
 package bloomfilter
 
 import (
+	"errors"
 	"fmt"
 	"time"
 )
 
+var (
+	// ErrFilteredOut 表示 key 被布隆过滤器拦截，一定不存在
+	ErrFilteredOut = errors.New("key filtered out by bloom filter")
+	// ErrNotFound 表示 key 通过了布隆过滤器（误判），但数据库中不存在
+	ErrNotFound = errors.New("key not found")
+)
+
 // MockRedis 模拟 Redis 客户端
 type MockRedis struct {
 	cache map[string]string
@@ -70,11 +78,12 @@ func NewCacheWithBloomFilter(redis *MockRedis, db *MockDatabase, expectedElement
 }
 
 // GetData 获取数据，使用布隆过滤器防止缓存穿透
+// key 不存在时返回的错误包装了 ErrFilteredOut 或 ErrNotFound，可用 errors.Is 区分
 func (c *CacheWithBloomFilter) GetData(key string) (string, error) {
 	// 第一步：检查布隆过滤器
 	if !c.bloomFilter.Contains([]byte(key)) {
 		// 布隆过滤器说这个 key 一定不存在，直接返回
-		return "", fmt.Errorf("key not found in bloom filter: %s", key)
+		return "", fmt.Errorf("%w: %s", ErrFilteredOut, key)
 	}
 	
 	// 第二步：查询 Redis 缓存
@@ -92,7 +101,7 @@ func (c *CacheWithBloomFilter) GetData(key string) (string, error) {
 	}
 	
 	// 数据库中也不存在
-	return "", fmt.Errorf("key not found: %s", key)
+	return "", fmt.Errorf("%w: %s", ErrNotFound, key)
 }
 
 // Example 使用示例
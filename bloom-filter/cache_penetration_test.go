@@ -1,6 +1,7 @@
 package bloomfilter
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 )
@@ -36,6 +37,23 @@ func TestCacheWithBloomFilter(t *testing.T) {
 	}
 }
 
+// TestGetDataErrors 测试两种不存在的情况可以用 errors.Is 区分
+func TestGetDataErrors(t *testing.T) {
+	cache := NewCacheWithBloomFilter(NewMockRedis(), NewMockDatabase(), 100)
+
+	_, err := cache.GetData("invalid:999")
+	if !errors.Is(err, ErrFilteredOut) || errors.Is(err, ErrNotFound) {
+		t.Errorf("被布隆过滤器拦截时应返回 ErrFilteredOut, 实际: %v", err)
+	}
+
+	// 模拟误判: key 在布隆过滤器中但数据库中不存在
+	cache.bloomFilter.Add([]byte("ghost:1"))
+	_, err = cache.GetData("ghost:1")
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrFilteredOut) {
+		t.Errorf("数据库中不存在时应返回 ErrNotFound, 实际: %v", err)
+	}
+}
+
 // TestCachePenetrationPrevention 测试缓存穿透防护
 func TestCachePenetrationPrevention(t *testing.T) {
 	redis := NewMockRedis()
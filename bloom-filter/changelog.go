@@ -0,0 +1,68 @@
+package bloomfilter
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrChangeLogOverflow 表示变更日志已满，有元素未被记录
+// 副本无法通过日志追平，需要用 MarshalBinary 等方式全量同步
+var ErrChangeLogOverflow = errors.New("bloomfilter: change log overflow")
+
+// changeLog 记录添加到过滤器的元素，用于增量复制
+type changeLog struct {
+	mu         sync.Mutex
+	entries    [][]byte
+	maxEntries int  // 最多保留的元素数量
+	overflowed bool // 自上次取出以来是否丢弃过元素
+}
+
+// EnableChangeLog 开启变更日志，之后 Add 的每个元素都会被复制一份记录下来
+// 副本定期通过 DrainChangeLog 取出并用 AddAll 应用，实现最终一致的分布式过滤器。
+// 日志最多保留 maxEntries 个元素，不及时取出时新元素被丢弃而不是阻塞 Add，
+// 下一次 DrainChangeLog 返回 ErrChangeLogOverflow 提示副本需要全量同步。
+// 只记录通过 Add、AddAll、TryAdd、AddWithResult 添加的元素，AddHash 没有原始数据，不会被记录
+func (bf *BloomFilter) EnableChangeLog(maxEntries int) {
+	bf.changeLog = &changeLog{maxEntries: maxEntries}
+}
+
+// DisableChangeLog 关闭变更日志并丢弃未取出的记录
+func (bf *BloomFilter) DisableChangeLog() {
+	bf.changeLog = nil
+}
+
+// DrainChangeLog 取出并清空自上次调用以来记录的元素
+// 期间有元素因日志已满被丢弃时，返回已记录的元素和 ErrChangeLogOverflow。
+// 未开启变更日志时返回 nil, nil
+func (bf *BloomFilter) DrainChangeLog() ([][]byte, error) {
+	if bf.changeLog == nil {
+		return nil, nil
+	}
+	return bf.changeLog.drain()
+}
+
+// append 复制并记录一个元素，日志已满时丢弃
+func (l *changeLog) append(data []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.entries) >= l.maxEntries {
+		l.overflowed = true
+		return
+	}
+	l.entries = append(l.entries, append([]byte(nil), data...))
+}
+
+// drain 取出所有记录并重置溢出标记
+func (l *changeLog) drain() ([][]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := l.entries
+	l.entries = nil
+	if l.overflowed {
+		l.overflowed = false
+		return entries, ErrChangeLogOverflow
+	}
+	return entries, nil
+}
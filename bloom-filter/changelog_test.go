@@ -0,0 +1,62 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestChangeLogReplication 测试通过变更日志把主过滤器的添加同步到副本
+func TestChangeLogReplication(t *testing.T) {
+	primary := NewBloomFilter(1000, 0.01)
+	replica := NewBloomFilter(1000, 0.01)
+	primary.EnableChangeLog(1000)
+
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 100; i++ {
+			primary.Add([]byte(fmt.Sprintf("r%d-item%d", round, i)))
+		}
+
+		entries, err := primary.DrainChangeLog()
+		if err != nil {
+			t.Fatalf("取出变更日志失败: %v", err)
+		}
+		if len(entries) != 100 {
+			t.Fatalf("第 %d 轮应取出 100 条记录, 实际: %d", round, len(entries))
+		}
+		replica.AddAll(entries)
+
+		if !primary.Equal(replica) {
+			t.Fatalf("第 %d 轮同步后副本应与主过滤器一致", round)
+		}
+	}
+
+	if entries, err := primary.DrainChangeLog(); len(entries) != 0 || err != nil {
+		t.Errorf("取出后日志应为空, 实际: %d 条, %v", len(entries), err)
+	}
+}
+
+// TestChangeLogOverflow 测试日志已满时丢弃新元素并报告溢出
+func TestChangeLogOverflow(t *testing.T) {
+	bf := NewBloomFilter(1000, 0.01)
+	bf.EnableChangeLog(10)
+
+	for i := 0; i < 15; i++ {
+		bf.Add([]byte(fmt.Sprintf("item%d", i)))
+	}
+	if !bf.Contains([]byte("item14")) {
+		t.Error("日志已满时 Add 仍应生效")
+	}
+
+	entries, err := bf.DrainChangeLog()
+	if err != ErrChangeLogOverflow {
+		t.Errorf("期望 ErrChangeLogOverflow, 实际: %v", err)
+	}
+	if len(entries) != 10 {
+		t.Errorf("应保留 10 条记录, 实际: %d", len(entries))
+	}
+
+	bf.Add([]byte("next"))
+	if entries, err := bf.DrainChangeLog(); err != nil || len(entries) != 1 {
+		t.Errorf("溢出标记应在取出后重置, 实际: %d 条, %v", len(entries), err)
+	}
+}
@@ -0,0 +1,22 @@
+package bloomfilter
+
+import "sync/atomic"
+
+// NewConcurrentBloomFilter 创建一个可并发读写的布隆过滤器
+// 位图以原子操作读写，Add、Contains、Clear 可以在多个 goroutine 中同时调用而无需加锁。
+// 原子操作比普通读写稍慢，只在需要并发访问时使用
+func NewConcurrentBloomFilter(n int, p float64) *BloomFilter {
+	bf := NewBloomFilter(n, p)
+	bf.concurrent = true
+	return bf
+}
+
+// atomicOr 原子地将 mask 按位或到 *addr
+func atomicOr(addr *uint64, mask uint64) {
+	for {
+		old := atomic.LoadUint64(addr)
+		if old&mask == mask || atomic.CompareAndSwapUint64(addr, old, old|mask) {
+			return
+		}
+	}
+}
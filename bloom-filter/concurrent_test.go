@@ -0,0 +1,60 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentAddContains 测试并发模式下多个 goroutine 同时添加和查询
+func TestConcurrentAddContains(t *testing.T) {
+	bf := NewConcurrentBloomFilter(10000, 0.01)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := []byte(fmt.Sprintf("w%d-item%d", w, i))
+				bf.Add(key)
+				if !bf.Contains(key) {
+					t.Errorf("%s 添加后应该存在", key)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// TestConcurrentClear 测试 Clear 与 Add、Contains 并发执行没有数据竞争（配合 -race 运行）
+func TestConcurrentClear(t *testing.T) {
+	bf := NewConcurrentBloomFilter(1000, 0.01)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := []byte(fmt.Sprintf("w%d-item%d", w, i%100))
+				bf.Add(key)
+				bf.Contains(key)
+			}
+		}(w)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			bf.Clear()
+		}
+	}()
+	wg.Wait()
+
+	bf.Clear()
+	if bf.FillRatio() != 0 {
+		t.Errorf("清空后填充率应为 0, 实际: %.4f", bf.FillRatio())
+	}
+}
@@ -0,0 +1,45 @@
+package bloomfilter
+
+// DeletableBloomFilter 通过墓碑过滤器近似支持删除的布隆过滤器
+// 比计数布隆过滤器节省内存，代价是精度更低：
+//   - 删除一个元素会把它写入墓碑过滤器，墓碑过滤器自身的误判会让
+//     从未删除过的元素被误认为已删除，即可能出现假阴性
+//   - 已删除的元素再次 Add 后仍被视为已删除，直到调用 Rebuild
+//   - 墓碑过滤器只增不减，删除越多误判越多，需要定期 Rebuild 回收空间
+type DeletableBloomFilter struct {
+	main    *BloomFilter // 存放所有添加过的元素
+	deleted *BloomFilter // 存放已删除的元素（墓碑）
+}
+
+// NewDeletableBloomFilter 创建一个支持近似删除的布隆过滤器
+// n: 预计插入的元素数量
+// deletes: 两次重建之间预计删除的元素数量
+// p: 期望的误判率 (0 < p < 1)
+func NewDeletableBloomFilter(n, deletes int, p float64) *DeletableBloomFilter {
+	return &DeletableBloomFilter{
+		main:    NewBloomFilter(n, p),
+		deleted: NewBloomFilter(deletes, p),
+	}
+}
+
+// Add 添加元素
+func (df *DeletableBloomFilter) Add(data []byte) {
+	df.main.Add(data)
+}
+
+// Delete 删除元素，将其写入墓碑过滤器
+func (df *DeletableBloomFilter) Delete(data []byte) {
+	df.deleted.Add(data)
+}
+
+// Contains 检查元素是否可能存在：在主过滤器中且不在墓碑过滤器中
+func (df *DeletableBloomFilter) Contains(data []byte) bool {
+	return df.main.Contains(data) && !df.deleted.Contains(data)
+}
+
+// Rebuild 清空主过滤器和墓碑过滤器，并通过 reAddKeys 重新添加仍然存在的元素
+func (df *DeletableBloomFilter) Rebuild(reAddKeys func(add func([]byte))) {
+	df.main.Clear()
+	df.deleted.Clear()
+	reAddKeys(df.main.Add)
+}
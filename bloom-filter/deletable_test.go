@@ -0,0 +1,40 @@
+package bloomfilter
+
+import "testing"
+
+// TestDeletableBloomFilter 测试添加、删除与重建
+func TestDeletableBloomFilter(t *testing.T) {
+	df := NewDeletableBloomFilter(1000, 100, 0.01)
+
+	df.Add([]byte("apple"))
+	df.Add([]byte("banana"))
+	if !df.Contains([]byte("apple")) || !df.Contains([]byte("banana")) {
+		t.Fatal("应该包含添加的元素")
+	}
+
+	df.Delete([]byte("apple"))
+	if df.Contains([]byte("apple")) {
+		t.Error("删除后不应包含 apple")
+	}
+	if !df.Contains([]byte("banana")) {
+		t.Error("删除 apple 不应影响 banana")
+	}
+
+	// 删除后再次添加仍被视为已删除
+	df.Add([]byte("apple"))
+	if df.Contains([]byte("apple")) {
+		t.Error("重建前再次添加的已删除元素仍被视为已删除")
+	}
+
+	// 重建后墓碑被清空
+	df.Rebuild(func(add func([]byte)) {
+		add([]byte("apple"))
+		add([]byte("banana"))
+	})
+	if !df.Contains([]byte("apple")) {
+		t.Error("重建后应该包含重新添加的 apple")
+	}
+	if !df.Contains([]byte("banana")) {
+		t.Error("重建后应该包含 banana")
+	}
+}
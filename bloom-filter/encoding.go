@@ -0,0 +1,205 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+)
+
+// headerSize 序列化头部长度：位图大小、哈希函数数量、校验和各 8 字节
+const headerSize = 24
+
+// streamChunkWords 流式读写时每批处理的字数
+const streamChunkWords = 512
+
+// MaxDecodeBits 是反序列化时接受的最大位图大小（位），默认 1<<34 位即 2GiB
+// 头部中的位图大小来自不可信的输入，超过上限时直接返回错误，避免按伪造的大小分配内存；
+// 确实需要更大的过滤器时可以调大
+var MaxDecodeBits uint64 = 1 << 34
+
+// parseHeader 解析并校验序列化头部
+func parseHeader(header []byte) (size, hashCount int, checksum uint64, err error) {
+	rawSize := binary.LittleEndian.Uint64(header[0:])
+	rawHashCount := binary.LittleEndian.Uint64(header[8:])
+	checksum = binary.LittleEndian.Uint64(header[16:])
+
+	if rawSize == 0 || rawHashCount == 0 || rawHashCount > math.MaxInt32 {
+		return 0, 0, 0, fmt.Errorf("invalid header: size=%d, hashCount=%d", rawSize, rawHashCount)
+	}
+	if rawSize > MaxDecodeBits || rawSize > math.MaxInt-63 {
+		return 0, 0, 0, fmt.Errorf("size %d exceeds limit %d", rawSize, MaxDecodeBits)
+	}
+	return int(rawSize), int(rawHashCount), checksum, nil
+}
+
+// Checksum 计算位图及参数的校验和
+// 可用于低成本地验证过滤器在序列化或传输后是否完好
+func (bf *BloomFilter) Checksum() uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+
+	binary.LittleEndian.PutUint64(buf[:], uint64(bf.size))
+	h.Write(buf[:])
+	binary.LittleEndian.PutUint64(buf[:], uint64(bf.hashCount))
+	h.Write(buf[:])
+	for _, w := range bf.bits {
+		binary.LittleEndian.PutUint64(buf[:], w)
+		h.Write(buf[:])
+	}
+	return h.Sum64()
+}
+
+// MarshalBinary 将布隆过滤器编码为字节序列
+// 格式：位图大小 | 哈希函数数量 | 校验和 | 位图（均为小端序 uint64）
+func (bf *BloomFilter) MarshalBinary() ([]byte, error) {
+	data := make([]byte, headerSize+len(bf.bits)*8)
+	binary.LittleEndian.PutUint64(data[0:], uint64(bf.size))
+	binary.LittleEndian.PutUint64(data[8:], uint64(bf.hashCount))
+	binary.LittleEndian.PutUint64(data[16:], bf.Checksum())
+	for i, w := range bf.bits {
+		binary.LittleEndian.PutUint64(data[headerSize+i*8:], w)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary 从 MarshalBinary 的输出恢复布隆过滤器
+// 数据长度或校验和不一致时返回错误，且不修改原过滤器
+func (bf *BloomFilter) UnmarshalBinary(data []byte) error {
+	if len(data) < headerSize {
+		return fmt.Errorf("data too short: %d bytes", len(data))
+	}
+	size, hashCount, checksum, err := parseHeader(data)
+	if err != nil {
+		return err
+	}
+	if len(data) != headerSize+wordCount(size)*8 {
+		return fmt.Errorf("data length %d does not match size %d", len(data), size)
+	}
+
+	bits := make([]uint64, wordCount(size))
+	for i := range bits {
+		bits[i] = binary.LittleEndian.Uint64(data[headerSize+i*8:])
+	}
+
+	decoded := &BloomFilter{}
+	decoded.setState(bits, size, hashCount)
+	if decoded.Checksum() != checksum {
+		return fmt.Errorf("checksum mismatch")
+	}
+
+	bf.setState(bits, size, hashCount)
+	return nil
+}
+
+// WriteTo 将布隆过滤器以 MarshalBinary 相同的格式流式写入 w
+// 位图分批写出，无需先在内存中构造完整的字节序列
+func (bf *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+
+	var header [headerSize]byte
+	binary.LittleEndian.PutUint64(header[0:], uint64(bf.size))
+	binary.LittleEndian.PutUint64(header[8:], uint64(bf.hashCount))
+	binary.LittleEndian.PutUint64(header[16:], bf.Checksum())
+	n, err := w.Write(header[:])
+	total += int64(n)
+	if err != nil {
+		return total, err
+	}
+
+	buf := make([]byte, streamChunkWords*8)
+	for start := 0; start < len(bf.bits); start += streamChunkWords {
+		end := start + streamChunkWords
+		if end > len(bf.bits) {
+			end = len(bf.bits)
+		}
+		chunk := buf[:(end-start)*8]
+		for i, word := range bf.bits[start:end] {
+			binary.LittleEndian.PutUint64(chunk[i*8:], word)
+		}
+
+		n, err := w.Write(chunk)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ReadFrom 从 r 流式读取 WriteTo 写出的布隆过滤器
+// 数据不完整、校验和不一致或位图大小超过 MaxDecodeBits 时返回错误，且不修改原过滤器。
+// 位图随数据实际到达逐批增长，头部声明的大小与实际数据不符时不会预先分配整个位图
+func (bf *BloomFilter) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+
+	var header [headerSize]byte
+	n, err := io.ReadFull(r, header[:])
+	total += int64(n)
+	if err != nil {
+		return total, err
+	}
+	size, hashCount, checksum, err := parseHeader(header[:])
+	if err != nil {
+		return total, err
+	}
+
+	words := wordCount(size)
+	bits := make([]uint64, 0, min(words, streamChunkWords))
+	buf := make([]byte, streamChunkWords*8)
+	for len(bits) < words {
+		chunk := buf[:min(words-len(bits), streamChunkWords)*8]
+
+		n, err := io.ReadFull(r, chunk)
+		total += int64(n)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return total, err
+		}
+		for i := 0; i < len(chunk); i += 8 {
+			bits = append(bits, binary.LittleEndian.Uint64(chunk[i:]))
+		}
+	}
+
+	decoded := &BloomFilter{}
+	decoded.setState(bits, size, hashCount)
+	if decoded.Checksum() != checksum {
+		return total, fmt.Errorf("checksum mismatch")
+	}
+
+	bf.setState(bits, size, hashCount)
+	return total, nil
+}
+
+// MarshalCompressed 将布隆过滤器编码为 zlib 压缩的字节序列
+// 解压后与 MarshalBinary 的格式相同。填充率低的过滤器（按 100 万元素创建但只插入了少量元素）
+// 位图大部分为 0，压缩后远小于 MarshalBinary 的输出；接近满载时压缩几乎没有收益
+func (bf *BloomFilter) MarshalCompressed() ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := bf.WriteTo(zw); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalCompressed 从 MarshalCompressed 的输出恢复布隆过滤器
+// 数据损坏或校验和不一致时返回错误，且不修改原过滤器
+func (bf *BloomFilter) UnmarshalCompressed(data []byte) error {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	_, err = bf.ReadFrom(zr)
+	return err
+}
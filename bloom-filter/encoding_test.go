@@ -0,0 +1,184 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+)
+
+// newFilledFilter 创建一个添加了 count 个元素的布隆过滤器
+func newFilledFilter(n, count int) *BloomFilter {
+	bf := NewBloomFilter(n, 0.01)
+	for i := 0; i < count; i++ {
+		bf.Add([]byte(fmt.Sprintf("item%d", i)))
+	}
+	return bf
+}
+
+// TestMarshalRoundTrip 测试序列化与反序列化
+func TestMarshalRoundTrip(t *testing.T) {
+	bf := newFilledFilter(1000, 100)
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatalf("序列化失败: %v", err)
+	}
+
+	restored := &BloomFilter{}
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("反序列化失败: %v", err)
+	}
+	if !restored.Equal(bf) {
+		t.Error("反序列化后的过滤器应该与原过滤器一致")
+	}
+	if restored.Checksum() != bf.Checksum() {
+		t.Error("反序列化后的校验和应该与原过滤器一致")
+	}
+}
+
+// TestEqual 测试过滤器比较
+func TestEqual(t *testing.T) {
+	a := newFilledFilter(1000, 100)
+	b := newFilledFilter(1000, 100)
+	if !a.Equal(b) {
+		t.Error("相同参数和元素的过滤器应该相等")
+	}
+
+	b.Add([]byte("extra"))
+	if a.Equal(b) {
+		t.Error("添加不同元素后过滤器不应相等")
+	}
+	if a.Equal(NewBloomFilter(2000, 0.01)) {
+		t.Error("大小不同的过滤器不应相等")
+	}
+}
+
+// TestUnmarshalCorrupted 测试损坏数据被校验和检测
+func TestUnmarshalCorrupted(t *testing.T) {
+	bf := newFilledFilter(1000, 100)
+	data, _ := bf.MarshalBinary()
+
+	// 翻转位图中的一位
+	data[len(data)-1] ^= 0x01
+
+	restored := NewBloomFilter(10, 0.01)
+	if err := restored.UnmarshalBinary(data); err == nil {
+		t.Error("损坏的数据应该返回错误")
+	}
+	if restored.Size() != NewBloomFilter(10, 0.01).Size() {
+		t.Error("反序列化失败时不应修改原过滤器")
+	}
+
+	if err := restored.UnmarshalBinary(data[:10]); err == nil {
+		t.Error("过短的数据应该返回错误")
+	}
+}
+
+// TestStreamRoundTrip 测试流式读写
+func TestStreamRoundTrip(t *testing.T) {
+	// 位图超过一个批次，覆盖分批读写
+	bf := newFilledFilter(100000, 1000)
+
+	var buf bytes.Buffer
+	written, err := bf.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+
+	data, _ := bf.MarshalBinary()
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Error("流式写入的格式应该与 MarshalBinary 一致")
+	}
+
+	restored := &BloomFilter{}
+	read, err := restored.ReadFrom(&buf)
+	if err != nil {
+		t.Fatalf("读取失败: %v", err)
+	}
+	if read != written {
+		t.Errorf("读取字节数 %d 应与写入字节数 %d 一致", read, written)
+	}
+	if !restored.Equal(bf) {
+		t.Error("读取后的过滤器应该与原过滤器一致")
+	}
+
+	// 数据不完整
+	if _, err := restored.ReadFrom(bytes.NewReader(data[:len(data)/2])); err == nil {
+		t.Error("数据不完整时应该返回错误")
+	}
+}
+
+// TestCompressedRoundTrip 测试稀疏过滤器压缩后明显变小且能正确恢复
+func TestCompressedRoundTrip(t *testing.T) {
+	bf := newFilledFilter(1000000, 1000)
+
+	raw, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatalf("序列化失败: %v", err)
+	}
+	compressed, err := bf.MarshalCompressed()
+	if err != nil {
+		t.Fatalf("压缩序列化失败: %v", err)
+	}
+	t.Logf("未压缩: %d 字节, 压缩后: %d 字节", len(raw), len(compressed))
+	if len(compressed)*10 > len(raw) {
+		t.Errorf("稀疏过滤器压缩后应小于原大小的 1/10, 实际: %d / %d", len(compressed), len(raw))
+	}
+
+	decoded := &BloomFilter{}
+	if err := decoded.UnmarshalCompressed(compressed); err != nil {
+		t.Fatalf("解压失败: %v", err)
+	}
+	if !decoded.Equal(bf) {
+		t.Error("解压后的过滤器应与原过滤器相等")
+	}
+
+	if err := decoded.UnmarshalCompressed(compressed[:len(compressed)/2]); err == nil {
+		t.Error("截断的数据应该返回错误")
+	}
+	if !decoded.Equal(bf) {
+		t.Error("返回错误时不应修改原过滤器")
+	}
+}
+
+// forgedHeader 构造一个声明了 size 位、但没有位图数据的序列化头部
+func forgedHeader(size uint64) []byte {
+	header := make([]byte, headerSize)
+	binary.LittleEndian.PutUint64(header[0:], size)
+	binary.LittleEndian.PutUint64(header[8:], 7)
+	return header
+}
+
+// TestDecodeForgedSize 测试头部声明的位图大小不可信时返回错误而不是 panic 或分配巨大的内存
+func TestDecodeForgedSize(t *testing.T) {
+	bf := newFilledFilter(100, 10)
+	before, _ := bf.MarshalBinary()
+
+	for _, size := range []uint64{1 << 60, math.MaxUint64, MaxDecodeBits + 1} {
+		if _, err := bf.ReadFrom(bytes.NewReader(forgedHeader(size))); err == nil {
+			t.Errorf("size=%d: ReadFrom 应返回错误", size)
+		}
+		if err := bf.UnmarshalBinary(forgedHeader(size)); err == nil {
+			t.Errorf("size=%d: UnmarshalBinary 应返回错误", size)
+		}
+	}
+
+	// 上限以内但数据缺失: 读到数据结束即返回错误, 不会预先分配整个位图
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(forgedHeader(min(MaxDecodeBits, math.MaxInt32)))
+	zw.Close()
+	if err := bf.UnmarshalCompressed(compressed.Bytes()); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("数据不完整时应返回 io.ErrUnexpectedEOF, 实际: %v", err)
+	}
+
+	after, _ := bf.MarshalBinary()
+	if !bytes.Equal(before, after) {
+		t.Error("解码失败时不应修改原过滤器")
+	}
+}
@@ -0,0 +1,19 @@
+package bloomfilter
+
+// MeasureFalsePositiveRate 实测当前过滤器的误判率
+// probe 生成第 i 个探测 key，调用方必须保证这些 key 都没有加入过过滤器，
+// 否则命中会被计为误判。适合在新建的过滤器上线前校验是否满足预期误判率。
+// probes <= 0 时返回 0
+func (bf *BloomFilter) MeasureFalsePositiveRate(probe func(i int) []byte, probes int) float64 {
+	if probes <= 0 {
+		return 0
+	}
+
+	falsePositive := 0
+	for i := 0; i < probes; i++ {
+		if bf.Contains(probe(i)) {
+			falsePositive++
+		}
+	}
+	return float64(falsePositive) / float64(probes)
+}
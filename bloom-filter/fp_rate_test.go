@@ -0,0 +1,34 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestMeasureFalsePositiveRate 测试实测误判率接近配置的 p
+func TestMeasureFalsePositiveRate(t *testing.T) {
+	n := 10000
+	p := 0.01
+	bf := NewBloomFilter(n, p)
+	for i := 0; i < n; i++ {
+		bf.Add([]byte(fmt.Sprintf("item%d", i)))
+	}
+
+	rate := bf.MeasureFalsePositiveRate(func(i int) []byte {
+		return []byte(fmt.Sprintf("absent%d", i))
+	}, 20000)
+	t.Logf("期望误判率: %.4f, 实测误判率: %.4f", p, rate)
+
+	if rate > p*2 {
+		t.Errorf("实测误判率 %.4f 远高于期望值 %.4f", rate, p)
+	}
+
+	if got := NewBloomFilter(n, p).MeasureFalsePositiveRate(func(i int) []byte {
+		return []byte(fmt.Sprintf("absent%d", i))
+	}, 1000); got != 0 {
+		t.Errorf("空过滤器的误判率应为 0, 实际: %.4f", got)
+	}
+	if got := bf.MeasureFalsePositiveRate(nil, 0); got != 0 {
+		t.Errorf("probes 为 0 时应返回 0, 实际: %.4f", got)
+	}
+}
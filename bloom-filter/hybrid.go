@@ -0,0 +1,43 @@
+package bloomfilter
+
+// HybridFilter 精确集合与布隆过滤器组合的过滤器
+// 热点 key 存放在精确集合中，永远不会误判；其余长尾 key 使用布隆过滤器
+type HybridFilter struct {
+	hot    map[string]struct{} // 热点 key 的精确集合
+	filter *BloomFilter        // 长尾 key 的布隆过滤器
+}
+
+// NewHybridFilter 创建一个组合过滤器
+// n: 预计插入布隆过滤器的长尾元素数量
+// p: 布隆过滤器的期望误判率 (0 < p < 1)
+func NewHybridFilter(n int, p float64) *HybridFilter {
+	return &HybridFilter{
+		hot:    make(map[string]struct{}),
+		filter: NewBloomFilter(n, p),
+	}
+}
+
+// AddHot 添加热点元素到精确集合
+func (hf *HybridFilter) AddHot(data []byte) {
+	hf.hot[string(data)] = struct{}{}
+}
+
+// Add 添加长尾元素到布隆过滤器
+func (hf *HybridFilter) Add(data []byte) {
+	hf.filter.Add(data)
+}
+
+// Contains 检查元素是否可能存在
+// 先查精确集合，命中即确定存在；否则按布隆过滤器的语义返回
+func (hf *HybridFilter) Contains(data []byte) bool {
+	if _, ok := hf.hot[string(data)]; ok {
+		return true
+	}
+	return hf.filter.Contains(data)
+}
+
+// ContainsHot 检查元素是否在热点集合中，结果是精确的
+func (hf *HybridFilter) ContainsHot(data []byte) bool {
+	_, ok := hf.hot[string(data)]
+	return ok
+}
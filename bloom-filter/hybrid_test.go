@@ -0,0 +1,49 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestHybridFilter 测试热点 key 不会误判，长尾 key 保持布隆过滤器语义
+func TestHybridFilter(t *testing.T) {
+	// 布隆过滤器容量很小，长尾部分会产生大量误判
+	hf := NewHybridFilter(10, 0.1)
+
+	for i := 0; i < 10; i++ {
+		hf.AddHot([]byte(fmt.Sprintf("hot:%d", i)))
+	}
+	for i := 0; i < 200; i++ {
+		hf.Add([]byte(fmt.Sprintf("cold:%d", i)))
+	}
+
+	// 所有添加过的元素都存在
+	for i := 0; i < 10; i++ {
+		if !hf.Contains([]byte(fmt.Sprintf("hot:%d", i))) {
+			t.Fatalf("应该包含 hot:%d", i)
+		}
+	}
+	for i := 0; i < 200; i++ {
+		if !hf.Contains([]byte(fmt.Sprintf("cold:%d", i))) {
+			t.Fatalf("应该包含 cold:%d", i)
+		}
+	}
+
+	// 热点集合是精确的，不会误判
+	for i := 10; i < 1000; i++ {
+		if hf.ContainsHot([]byte(fmt.Sprintf("hot:%d", i))) {
+			t.Fatalf("热点集合不应误判 hot:%d", i)
+		}
+	}
+
+	// 长尾部分超出容量后出现误判，说明仍是布隆过滤器语义
+	falsePositive := 0
+	for i := 0; i < 1000; i++ {
+		if hf.Contains([]byte(fmt.Sprintf("absent:%d", i))) {
+			falsePositive++
+		}
+	}
+	if falsePositive == 0 {
+		t.Error("超出容量的长尾部分应该出现误判")
+	}
+}
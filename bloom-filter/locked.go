@@ -0,0 +1,54 @@
+package bloomfilter
+
+import "sync"
+
+// SyncBloomFilter 用读写锁保护的布隆过滤器
+// 与 NewConcurrentBloomFilter 的原子模式相比，查询只需读锁、位图按普通方式读写，
+// 适合读多写少、写入集中在预热阶段的场景
+type SyncBloomFilter struct {
+	mu sync.RWMutex
+	bf *BloomFilter
+}
+
+// NewSyncBloomFilter 创建一个用读写锁保护的布隆过滤器
+func NewSyncBloomFilter(n int, p float64) *SyncBloomFilter {
+	return &SyncBloomFilter{bf: NewBloomFilter(n, p)}
+}
+
+// Add 添加元素，每次调用获取一次写锁
+func (s *SyncBloomFilter) Add(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bf.Add(data)
+}
+
+// AddAll 批量添加元素，整批只获取一次写锁
+// 预热时比逐个 Add 快得多，但整批添加期间所有查询都会被阻塞，
+// 批量很大又有在线查询时应拆分成较小的批次
+func (s *SyncBloomFilter) AddAll(keys [][]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bf.AddAll(keys)
+}
+
+// Contains 检查元素是否可能存在
+func (s *SyncBloomFilter) Contains(data []byte) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.Contains(data)
+}
+
+// EnableAdaptiveOrder 开启自适应检查顺序，见 BloomFilter.EnableAdaptiveOrder
+// 统计以原子操作更新，开启后查询仍只需读锁
+func (s *SyncBloomFilter) EnableAdaptiveOrder(every int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bf.EnableAdaptiveOrder(every)
+}
+
+// Clear 清空过滤器
+func (s *SyncBloomFilter) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bf.Clear()
+}
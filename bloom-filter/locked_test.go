@@ -0,0 +1,86 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestSyncBloomFilter 测试批量添加与并发查询
+func TestSyncBloomFilter(t *testing.T) {
+	s := NewSyncBloomFilter(10000, 0.01)
+	keys := make([][]byte, 5000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key-%d", i))
+	}
+
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, key := range keys {
+				s.Contains(key)
+			}
+		}()
+	}
+	s.AddAll(keys[:2500])
+	for _, key := range keys[2500:] {
+		s.Add(key)
+	}
+	wg.Wait()
+
+	for _, key := range keys {
+		if !s.Contains(key) {
+			t.Fatalf("%s 添加后应该存在", key)
+		}
+	}
+	s.Clear()
+	if s.Contains(keys[0]) {
+		t.Error("清空后不应存在")
+	}
+}
+
+// benchmarkPreheat 在并发查询的同时预热 10000 个元素, add 决定加锁方式
+func benchmarkPreheat(b *testing.B, add func(s *SyncBloomFilter, keys [][]byte)) {
+	keys := make([][]byte, 10000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key-%d", i))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s := NewSyncBloomFilter(len(keys), 0.01)
+		var stop atomic.Bool
+		var wg sync.WaitGroup
+		for r := 0; r < 4; r++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; !stop.Load(); j++ {
+					s.Contains(keys[j%len(keys)])
+				}
+			}()
+		}
+		add(s, keys)
+		stop.Store(true)
+		wg.Wait()
+	}
+}
+
+// BenchmarkPreheatBatched 整批只加一次锁
+func BenchmarkPreheatBatched(b *testing.B) {
+	benchmarkPreheat(b, func(s *SyncBloomFilter, keys [][]byte) {
+		s.AddAll(keys)
+	})
+}
+
+// BenchmarkPreheatPerElement 每个元素加一次锁
+func BenchmarkPreheatPerElement(b *testing.B) {
+	benchmarkPreheat(b, func(s *SyncBloomFilter, keys [][]byte) {
+		for _, key := range keys {
+			s.Add(key)
+		}
+	})
+}
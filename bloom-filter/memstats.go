@@ -0,0 +1,34 @@
+package bloomfilter
+
+// MemStats 布隆过滤器的内存占用，用于容量规划
+type MemStats struct {
+	Bits      int // 位图大小（位）
+	Bytes     int // 位图实际占用的字节数，按 64 位字对齐
+	HashCount int // 哈希函数数量
+
+	// BytesPerElement 按 ApproxCount 估算的每个元素平均占用的字节数，
+	// 尚未添加元素或位图已全部为 1 无法估算时为 0
+	BytesPerElement float64
+}
+
+// SizeInBytes 返回位图实际占用的字节数
+// Size 返回的是位数，位图按 64 位字分配，字节数为字数的 8 倍
+func (bf *BloomFilter) SizeInBytes() int {
+	return len(bf.bits) * 8
+}
+
+// MemStats 返回过滤器的内存占用统计
+// BytesPerElement 需要统计置位数量，耗时与位图大小成正比
+func (bf *BloomFilter) MemStats() MemStats {
+	stats := MemStats{
+		Bits:      bf.size,
+		Bytes:     bf.SizeInBytes(),
+		HashCount: bf.hashCount,
+	}
+	if bf.FillRatio() < 1 {
+		if n := bf.ApproxCount(); n > 0 {
+			stats.BytesPerElement = float64(stats.Bytes) / float64(n)
+		}
+	}
+	return stats
+}
@@ -0,0 +1,41 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestSizeInBytes 测试字节数与按 64 位字分配的位图一致
+func TestSizeInBytes(t *testing.T) {
+	for _, n := range []int{1, 10, 1000, 100000} {
+		bf := NewBloomFilter(n, 0.01)
+		want := (bf.Size() + 63) / 64 * 8
+		if got := bf.SizeInBytes(); got != want {
+			t.Errorf("n=%d: 期望 %d 字节, 实际: %d", n, want, got)
+		}
+		if got := bf.SizeInBytes(); got < bf.Size()/8 {
+			t.Errorf("n=%d: 字节数 %d 不足以存放 %d 位", n, got, bf.Size())
+		}
+	}
+}
+
+// TestMemStats 测试内存统计
+func TestMemStats(t *testing.T) {
+	bf := NewBloomFilter(1000, 0.01)
+	stats := bf.MemStats()
+	if stats.Bits != bf.Size() || stats.Bytes != bf.SizeInBytes() || stats.HashCount != bf.HashCount() {
+		t.Errorf("统计与过滤器参数不一致: %+v", stats)
+	}
+	if stats.BytesPerElement != 0 {
+		t.Errorf("空过滤器无法估算每个元素的字节数, 实际: %v", stats.BytesPerElement)
+	}
+
+	for i := 0; i < 1000; i++ {
+		bf.Add([]byte(fmt.Sprintf("key-%d", i)))
+	}
+	stats = bf.MemStats()
+	// p=0.01 时每个元素约 9.6 位，即 1.2 字节
+	if stats.BytesPerElement < 1 || stats.BytesPerElement > 1.5 {
+		t.Errorf("每个元素的字节数期望约 1.2, 实际: %v", stats.BytesPerElement)
+	}
+}
@@ -0,0 +1,107 @@
+package bloomfilter
+
+// OverlayFilter 普通布隆过滤器加小型计数覆盖层的过滤器
+// 大部分元素只需要添加、不会删除，存放在每位 1 bit 的布隆过滤器中；
+// 少量需要删除的元素（如会话、临时封禁名单）存放在按其数量单独计算大小的计数覆盖层中，
+// 每个位置用 8 位计数器，可以精确地删除。
+//
+// 与对全部元素使用计数布隆过滤器相比，内存约为 m(n) + 8*m(deletable)，
+// 而不是 8*m(n)。代价是：
+//   - 只有通过 AddDeletable 添加的元素才能删除，Delete 对 Add 添加的元素无效
+//   - 查询需要检查两个过滤器，误判率约为两者之和
+//   - 覆盖层的元素数量超过 deletable 后误判率上升
+type OverlayFilter struct {
+	base    *BloomFilter    // 不可删除的元素
+	overlay *countingFilter // 可删除的元素
+}
+
+// NewOverlayFilter 创建一个带计数覆盖层的过滤器
+// n: 预计添加的不可删除元素数量
+// deletable: 同时存在的可删除元素数量上限
+// p: 每个过滤器的期望误判率 (0 < p < 1)
+func NewOverlayFilter(n, deletable int, p float64) *OverlayFilter {
+	return &OverlayFilter{
+		base:    NewBloomFilter(n, p),
+		overlay: newCountingFilter(deletable, p),
+	}
+}
+
+// Add 添加不可删除的元素
+func (of *OverlayFilter) Add(data []byte) {
+	of.base.Add(data)
+}
+
+// AddDeletable 添加之后可以删除的元素
+func (of *OverlayFilter) AddDeletable(data []byte) {
+	of.overlay.add(data)
+}
+
+// Delete 删除通过 AddDeletable 添加的元素
+// 元素不在覆盖层中时不做修改并返回 false；
+// 删除从未添加过的元素（覆盖层误判）会破坏其他元素的计数，调用方需保证只删除添加过的元素
+func (of *OverlayFilter) Delete(data []byte) bool {
+	return of.overlay.remove(data)
+}
+
+// Contains 检查元素是否可能存在：在布隆过滤器或覆盖层中
+func (of *OverlayFilter) Contains(data []byte) bool {
+	return of.base.Contains(data) || of.overlay.contains(data)
+}
+
+// countingFilter 每个位置使用 8 位计数器的计数布隆过滤器
+type countingFilter struct {
+	counts    []uint8
+	hashCount int
+}
+
+func newCountingFilter(n int, p float64) *countingFilter {
+	m := optimalSize(n, p)
+	return &countingFilter{
+		counts:    make([]uint8, m),
+		hashCount: optimalHashCount(n, m),
+	}
+}
+
+// position 计算第 i 个哈希函数对应的计数器
+func (cf *countingFilter) position(h1, h2 uint64, i int) int {
+	return int((h1 + uint64(i)*h2) % uint64(len(cf.counts)))
+}
+
+// add 增加元素对应的计数器，计数器达到上限后保持不变，之后不会再被减少
+func (cf *countingFilter) add(data []byte) {
+	h1, h2 := HashPair(data)
+	for i := 0; i < cf.hashCount; i++ {
+		pos := cf.position(h1, h2, i)
+		if cf.counts[pos] < 255 {
+			cf.counts[pos]++
+		}
+	}
+}
+
+// remove 减少元素对应的计数器，元素不存在时返回 false
+func (cf *countingFilter) remove(data []byte) bool {
+	if !cf.contains(data) {
+		return false
+	}
+
+	h1, h2 := HashPair(data)
+	for i := 0; i < cf.hashCount; i++ {
+		pos := cf.position(h1, h2, i)
+		// 饱和的计数器已经无法得知真实计数，保持不变以免产生假阴性
+		if cf.counts[pos] < 255 {
+			cf.counts[pos]--
+		}
+	}
+	return true
+}
+
+// contains 检查元素对应的计数器是否都大于 0
+func (cf *countingFilter) contains(data []byte) bool {
+	h1, h2 := HashPair(data)
+	for i := 0; i < cf.hashCount; i++ {
+		if cf.counts[cf.position(h1, h2, i)] == 0 {
+			return false
+		}
+	}
+	return true
+}
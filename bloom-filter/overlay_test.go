@@ -0,0 +1,74 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestOverlayFilterDelete 测试覆盖层中的元素可以删除, 布隆过滤器中的元素不受影响
+func TestOverlayFilterDelete(t *testing.T) {
+	of := NewOverlayFilter(1000, 100, 0.01)
+
+	for i := 0; i < 1000; i++ {
+		of.Add([]byte(fmt.Sprintf("permanent%d", i)))
+	}
+	for i := 0; i < 100; i++ {
+		of.AddDeletable([]byte(fmt.Sprintf("session%d", i)))
+	}
+	for i := 0; i < 100; i++ {
+		if !of.Contains([]byte(fmt.Sprintf("session%d", i))) {
+			t.Fatalf("session%d 应该存在", i)
+		}
+	}
+
+	// 删除一半的会话
+	for i := 0; i < 50; i++ {
+		if !of.Delete([]byte(fmt.Sprintf("session%d", i))) {
+			t.Errorf("session%d 应该删除成功", i)
+		}
+	}
+
+	stillPresent := 0
+	for i := 0; i < 50; i++ {
+		if of.Contains([]byte(fmt.Sprintf("session%d", i))) {
+			stillPresent++
+		}
+	}
+	if stillPresent > 5 {
+		t.Errorf("删除后仍被判定存在的元素过多: %d/50", stillPresent)
+	}
+	for i := 50; i < 100; i++ {
+		if !of.Contains([]byte(fmt.Sprintf("session%d", i))) {
+			t.Errorf("未删除的 session%d 应该存在", i)
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		if !of.Contains([]byte(fmt.Sprintf("permanent%d", i))) {
+			t.Fatalf("permanent%d 应该存在", i)
+		}
+	}
+}
+
+// TestOverlayFilterDeleteMissing 测试删除不在覆盖层中的元素
+func TestOverlayFilterDeleteMissing(t *testing.T) {
+	of := NewOverlayFilter(100, 10, 0.01)
+	of.Add([]byte("permanent"))
+
+	if of.Delete([]byte("permanent")) {
+		t.Error("Add 添加的元素不能删除")
+	}
+	if !of.Contains([]byte("permanent")) {
+		t.Error("删除失败后元素应该仍然存在")
+	}
+
+	of.AddDeletable([]byte("session"))
+	of.AddDeletable([]byte("session"))
+	of.Delete([]byte("session"))
+	if !of.Contains([]byte("session")) {
+		t.Error("添加两次只删除一次, 元素应该仍然存在")
+	}
+	of.Delete([]byte("session"))
+	if of.Contains([]byte("session")) {
+		t.Error("删除次数与添加次数相同后元素不应存在")
+	}
+}
@@ -0,0 +1,93 @@
+package bloomfilter
+
+import "sync"
+
+// ParallelBuild 使用多个 goroutine 并行构建布隆过滤器
+// keys: 需要添加的全部元素，过滤器按 len(keys) 和 p 确定大小
+// workers: 并行的 goroutine 数量
+//
+// 内存模型：每个 worker 负责 keys 的一个分片，写入自己独立的位图，互不共享，
+// 因此无需原子操作或加锁；全部 worker 结束后（WaitGroup 保证可见性）
+// 再把各分片位图按位或合并到最终的过滤器中。结果与串行构建完全一致。
+func ParallelBuild(keys [][]byte, p float64, workers int) *BloomFilter {
+	if len(keys) == 0 {
+		return NewBloomFilter(1, p)
+	}
+	bf := NewBloomFilter(len(keys), p)
+
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(keys) {
+		workers = len(keys)
+	}
+
+	partials := make([]*BloomFilter, workers)
+	chunk := (len(keys) + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start := w * chunk
+		end := start + chunk
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		partial := &BloomFilter{
+			bits:      make([]uint64, len(bf.bits)),
+			size:      bf.size,
+			hashCount: bf.hashCount,
+		}
+		partials[w] = partial
+
+		wg.Add(1)
+		go func(shard [][]byte) {
+			defer wg.Done()
+			for _, key := range shard {
+				partial.Add(key)
+			}
+		}(keys[start:end])
+	}
+	wg.Wait()
+
+	// 合并各 worker 的位图
+	for _, partial := range partials {
+		for i, w := range partial.bits {
+			bf.bits[i] |= w
+		}
+	}
+	return bf
+}
+
+// ContainsAllParallel 使用多个 goroutine 并行批量检查元素，结果与 ContainsAll 相同
+// 查询只读取位图，各 worker 写入结果切片中互不重叠的区间，因此无需加锁；
+// 查询期间如有并发的 Add，需要使用 NewConcurrentBloomFilter 创建的过滤器
+func (bf *BloomFilter) ContainsAllParallel(items [][]byte, workers int) []bool {
+	if workers <= 1 || len(items) <= 1 {
+		return bf.ContainsAll(items)
+	}
+	if workers > len(items) {
+		workers = len(items)
+	}
+
+	results := make([]bool, len(items))
+	chunk := (len(items) + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for start := 0; start < len(items); start += chunk {
+		end := start + chunk
+		if end > len(items) {
+			end = len(items)
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				results[i] = bf.Contains(items[i])
+			}
+		}(start, end)
+	}
+	wg.Wait()
+	return results
+}
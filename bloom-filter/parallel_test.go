@@ -0,0 +1,81 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestParallelBuild 测试并行构建与串行构建结果一致
+func TestParallelBuild(t *testing.T) {
+	keys := make([][]byte, 10000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("user:%d", i))
+	}
+
+	serial := NewBloomFilter(len(keys), 0.01)
+	for _, key := range keys {
+		serial.Add(key)
+	}
+
+	for _, workers := range []int{1, 4, 16} {
+		parallel := ParallelBuild(keys, 0.01, workers)
+		if !parallel.Equal(serial) {
+			t.Errorf("workers=%d: 并行构建结果应该与串行构建一致", workers)
+		}
+	}
+}
+
+// containsQueries 构建一个添加了一半查询元素的过滤器和 n 个查询
+func containsQueries(n int) (*BloomFilter, [][]byte) {
+	bf := NewBloomFilter(n, 0.01)
+	items := make([][]byte, n)
+	for i := range items {
+		items[i] = []byte(fmt.Sprintf("user:%d", i))
+		if i%2 == 0 {
+			bf.Add(items[i])
+		}
+	}
+	return bf, items
+}
+
+// TestContainsAllParallel 测试并行查询与串行查询结果一致
+func TestContainsAllParallel(t *testing.T) {
+	bf, items := containsQueries(10000)
+	serial := bf.ContainsAll(items)
+
+	for _, workers := range []int{1, 3, 16, 20000} {
+		parallel := bf.ContainsAllParallel(items, workers)
+		if len(parallel) != len(serial) {
+			t.Fatalf("workers=%d: 结果数量应为 %d, 实际: %d", workers, len(serial), len(parallel))
+		}
+		for i := range serial {
+			if parallel[i] != serial[i] {
+				t.Errorf("workers=%d: 第 %d 个结果应与串行查询一致", workers, i)
+				break
+			}
+		}
+	}
+	for i := 0; i < len(items); i += 2 {
+		if !serial[i] {
+			t.Fatalf("%s 已添加, 应该存在", items[i])
+		}
+	}
+}
+
+// BenchmarkContainsAll 串行批量查询
+func BenchmarkContainsAll(b *testing.B) {
+	bf, items := containsQueries(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.ContainsAll(items)
+	}
+}
+
+// BenchmarkContainsAllParallel 8 个 worker 并行批量查询
+func BenchmarkContainsAllParallel(b *testing.B) {
+	bf, items := containsQueries(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.ContainsAllParallel(items, 8)
+	}
+}
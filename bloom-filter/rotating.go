@@ -0,0 +1,89 @@
+package bloomfilter
+
+import (
+	"sync"
+	"time"
+)
+
+// RotatingBloomFilter 由多个按时间片轮换的子过滤器组成的布隆过滤器
+// 用于"最近一段时间内是否出现过"这类带时间窗口的判断：
+// Add 写入当前时间片，Contains 对所有时间片取或，Rotate 丢弃最旧的时间片，
+// 其中的元素随之过期。不需要计数即可得到近似的 TTL 语义：
+// 元素在添加后经过 slices-1 到 slices 次轮换后过期
+type RotatingBloomFilter struct {
+	mu      sync.RWMutex
+	filters []*BloomFilter // 按时间片排列的子过滤器，循环使用
+	current int            // 当前写入的时间片
+}
+
+// NewRotatingBloomFilter 创建一个按时间片轮换的布隆过滤器
+// slices: 时间片数量，小于 1 时按 1 处理
+// n: 每个时间片内预计插入的元素数量
+// p: 每个子过滤器的期望误判率 (0 < p < 1)，Contains 查询所有时间片，整体误判率约为 slices*p
+func NewRotatingBloomFilter(slices, n int, p float64) *RotatingBloomFilter {
+	if slices < 1 {
+		slices = 1
+	}
+	filters := make([]*BloomFilter, slices)
+	for i := range filters {
+		filters[i] = NewBloomFilter(n, p)
+	}
+	return &RotatingBloomFilter{filters: filters}
+}
+
+// Add 添加元素到当前时间片
+func (r *RotatingBloomFilter) Add(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.filters[r.current].Add(data)
+}
+
+// Contains 检查元素是否可能在任一未过期的时间片中
+func (r *RotatingBloomFilter) Contains(data []byte) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, f := range r.filters {
+		if f.Contains(data) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rotate 丢弃最旧的时间片，清空后作为新的当前时间片
+func (r *RotatingBloomFilter) Rotate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current = (r.current + 1) % len(r.filters)
+	r.filters[r.current].Clear()
+}
+
+// RotateEvery 启动后台协程，每隔 interval 调用一次 Rotate
+// 窗口长度约为 slices*interval。返回的 stop 函数停止轮换，可以多次调用
+func (r *RotatingBloomFilter) RotateEvery(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				r.Rotate()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
+
+// Slices 返回时间片数量
+func (r *RotatingBloomFilter) Slices() int {
+	return len(r.filters)
+}
@@ -0,0 +1,54 @@
+package bloomfilter
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRotatingBloomFilter 测试元素在轮换出所有时间片后过期
+func TestRotatingBloomFilter(t *testing.T) {
+	r := NewRotatingBloomFilter(3, 1000, 0.01)
+	r.Add([]byte("old"))
+
+	// 轮换 slices-1 次后仍在最旧的时间片中
+	for i := 0; i < 2; i++ {
+		r.Rotate()
+		if !r.Contains([]byte("old")) {
+			t.Fatalf("第 %d 次轮换后元素不应过期", i+1)
+		}
+	}
+	r.Add([]byte("new"))
+
+	r.Rotate()
+	if r.Contains([]byte("old")) {
+		t.Error("轮换出所有时间片后元素应过期")
+	}
+	if !r.Contains([]byte("new")) {
+		t.Error("较新的元素不应过期")
+	}
+
+	r.Rotate()
+	r.Rotate()
+	if r.Contains([]byte("new")) {
+		t.Error("轮换出所有时间片后元素应过期")
+	}
+}
+
+// TestRotateEvery 测试定时轮换
+func TestRotateEvery(t *testing.T) {
+	r := NewRotatingBloomFilter(2, 100, 0.01)
+	r.Add([]byte("key"))
+
+	stop := r.RotateEvery(10 * time.Millisecond)
+	defer stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Contains([]byte("key")) {
+		if time.Now().After(deadline) {
+			t.Fatal("定时轮换后元素应过期")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+	stop()
+}
@@ -0,0 +1,130 @@
+package bloomfilter
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// DefaultSaturationThreshold 默认的饱和填充率阈值
+// 按最优参数构建的过滤器在达到预期容量时填充率约为 0.5
+const DefaultSaturationThreshold = 0.5
+
+// ErrSaturated 表示过滤器已饱和，误判率显著升高，需要重建
+var ErrSaturated = errors.New("bloom filter saturated")
+
+// FillRatio 返回位图中为 1 的位所占的比例
+func (bf *BloomFilter) FillRatio() float64 {
+	ones := 0
+	for _, w := range bf.bits {
+		ones += bits.OnesCount64(w)
+	}
+	return float64(ones) / float64(bf.size)
+}
+
+// IsSaturated 判断填充率是否超过 threshold
+// 接近饱和时几乎所有查询都返回 true，过滤器失去拦截作用
+func (bf *BloomFilter) IsSaturated(threshold float64) bool {
+	return bf.FillRatio() > threshold
+}
+
+// SetSaturationThreshold 设置 TryAdd 判定饱和的填充率阈值
+func (bf *BloomFilter) SetSaturationThreshold(threshold float64) {
+	bf.saturationThreshold = threshold
+}
+
+// TryAdd 添加元素，添加后填充率超过饱和阈值时返回 ErrSaturated 提示调用方重建
+// 元素总是会被添加，返回错误不影响已添加元素的查询结果
+// 每次调用需要统计整个位图，开销为 O(m/64)，高频写入请使用 Add
+func (bf *BloomFilter) TryAdd(data []byte) error {
+	bf.Add(data)
+
+	threshold := bf.saturationThreshold
+	if threshold == 0 {
+		threshold = DefaultSaturationThreshold
+	}
+	if bf.IsSaturated(threshold) {
+		return ErrSaturated
+	}
+	return nil
+}
+
+// ApproxCount 根据填充率估算已添加的不同元素数量
+// 使用 n ≈ -(m/k) * ln(1 - X/m)，X 为置 1 的位数；重复添加的元素只计一次。
+// 位图全部为 1 时无法估算，返回 math.MaxInt
+func (bf *BloomFilter) ApproxCount() int {
+	fill := bf.FillRatio()
+	if fill >= 1 {
+		return math.MaxInt
+	}
+	n := -float64(bf.size) / float64(bf.hashCount) * math.Log(1-fill)
+	return int(math.Round(n))
+}
+
+// EstimatedFalsePositiveRate 根据当前填充率估算误判率
+// 不存在的元素的 k 个位置都恰好为 1 的概率，约为 fill^k
+func (bf *BloomFilter) EstimatedFalsePositiveRate() float64 {
+	return math.Pow(bf.FillRatio(), float64(bf.hashCount))
+}
+
+// ShouldRebuild 判断估算的误判率是否已超过 targetFPR
+// 缓存层可以据此自动触发重建，而不必预先猜测容量。
+// 按最优参数创建的过滤器，在添加的元素数量达到创建时的 n 附近时开始超过创建时的 p
+func (bf *BloomFilter) ShouldRebuild(targetFPR float64) bool {
+	return bf.EstimatedFalsePositiveRate() > targetFPR
+}
+
+// ForEachSetBit 按从小到大的顺序对每个为 1 的位调用 fn，用于观察哈希分布或比较两个过滤器
+// 逐个 64 位字处理，用 TrailingZeros64 跳过为 0 的位，开销与置位数量成正比
+func (bf *BloomFilter) ForEachSetBit(fn func(pos int)) {
+	for i, w := range bf.bits {
+		for w != 0 {
+			fn(i*64 + bits.TrailingZeros64(w))
+			w &= w - 1
+		}
+	}
+}
+
+// SetBitPositions 返回所有为 1 的位的位置，按从小到大排序
+func (bf *BloomFilter) SetBitPositions() []int {
+	positions := make([]int, 0)
+	bf.ForEachSetBit(func(pos int) {
+		positions = append(positions, pos)
+	})
+	return positions
+}
+
+// uniformityBuckets HashUniformity 划分的区段数
+const uniformityBuckets = 64
+
+// HashUniformity 衡量置位在位图中分布的均匀程度，返回 (0, 1] 之间的分数
+// 将位图等分为 64 个区段统计置位数，计算相对均匀分布的卡方统计量 chi2，
+// 分数为 min(1, 自由度/chi2)：均匀的哈希得分接近 1，置位集中在少数区段时分数很低。
+// 用于在插入一批样本后检查自定义哈希函数（见 NewBloomFilterWithHasher）的质量；
+// 没有置位时返回 1
+func (bf *BloomFilter) HashUniformity() float64 {
+	buckets := uniformityBuckets
+	if bf.size < buckets {
+		buckets = bf.size
+	}
+	counts := make([]int, buckets)
+	ones := 0
+	bf.ForEachSetBit(func(pos int) {
+		counts[pos*buckets/bf.size]++
+		ones++
+	})
+	if ones == 0 || buckets < 2 {
+		return 1
+	}
+
+	// 区段大小可能相差 1 位, 按各区段的位数计算期望值
+	chi2 := 0.0
+	for i, c := range counts {
+		// 区段 i 包含 pos*buckets/size == i 的位置, 即 [ceil(i*size/buckets), ceil((i+1)*size/buckets))
+		width := ((i+1)*bf.size+buckets-1)/buckets - (i*bf.size+buckets-1)/buckets
+		expected := float64(ones) * float64(width) / float64(bf.size)
+		d := float64(c) - expected
+		chi2 += d * d / expected
+	}
+	return math.Min(1, float64(buckets-1)/chi2)
+}
@@ -0,0 +1,138 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// TestSaturation 测试远超容量插入后检测到饱和
+func TestSaturation(t *testing.T) {
+	bf := NewBloomFilter(100, 0.01)
+	if bf.FillRatio() != 0 {
+		t.Errorf("空过滤器填充率应为 0, 实际: %.4f", bf.FillRatio())
+	}
+
+	// 预期容量内不饱和
+	for i := 0; i < 50; i++ {
+		if err := bf.TryAdd([]byte(fmt.Sprintf("item%d", i))); err != nil {
+			t.Fatalf("容量内添加不应饱和: %v", err)
+		}
+	}
+
+	// 远超容量后饱和
+	saturatedAt := -1
+	for i := 50; i < 1000; i++ {
+		if err := bf.TryAdd([]byte(fmt.Sprintf("item%d", i))); err == ErrSaturated {
+			saturatedAt = i
+			break
+		}
+	}
+	if saturatedAt < 0 {
+		t.Fatal("远超容量后应该检测到饱和")
+	}
+	t.Logf("插入第 %d 个元素时饱和, 填充率: %.4f", saturatedAt+1, bf.FillRatio())
+
+	if !bf.Contains([]byte(fmt.Sprintf("item%d", saturatedAt))) {
+		t.Error("返回饱和错误时元素仍应被添加")
+	}
+	if !bf.IsSaturated(0.5) {
+		t.Error("IsSaturated 应该返回 true")
+	}
+
+	// 自定义阈值
+	bf.SetSaturationThreshold(0.99)
+	if err := bf.TryAdd([]byte("extra")); err != nil {
+		t.Errorf("提高阈值后不应饱和: %v", err)
+	}
+}
+
+// TestShouldRebuild 测试超过预期容量后 ShouldRebuild 由 false 变为 true
+func TestShouldRebuild(t *testing.T) {
+	n := 1000
+	p := 0.01
+	bf := NewBloomFilter(n, p)
+
+	flipAt := -1
+	for i := 0; i < 2*n; i++ {
+		bf.Add([]byte(fmt.Sprintf("item%d", i)))
+		if flipAt < 0 && bf.ShouldRebuild(p) {
+			flipAt = i + 1
+		}
+	}
+	t.Logf("添加 %d 个元素后需要重建", flipAt)
+
+	if flipAt < n*9/10 || flipAt > n*12/10 {
+		t.Errorf("应在预期容量 %d 附近需要重建, 实际: %d", n, flipAt)
+	}
+
+	if got := bf.ApproxCount(); got < 2*n*9/10 || got > 2*n*11/10 {
+		t.Errorf("估算的元素数量应接近 %d, 实际: %d", 2*n, got)
+	}
+	if rate := bf.EstimatedFalsePositiveRate(); rate <= p {
+		t.Errorf("超过容量后估算误判率应高于 %.4f, 实际: %.4f", p, rate)
+	}
+}
+
+// TestSetBitPositions 测试枚举的置位位置与 Add 实际置位的位置完全一致
+func TestSetBitPositions(t *testing.T) {
+	bf := NewBloomFilter(1000, 0.01)
+	if got := bf.SetBitPositions(); len(got) != 0 {
+		t.Fatalf("空过滤器不应有置位, 实际: %v", got)
+	}
+
+	expected := make(map[int]bool)
+	for i := 0; i < 50; i++ {
+		for _, pos := range bf.AddWithResult([]byte(fmt.Sprintf("key-%d", i))) {
+			expected[pos] = true
+		}
+	}
+
+	got := bf.SetBitPositions()
+	if !sort.IntsAreSorted(got) {
+		t.Error("置位位置应按从小到大排序")
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("置位数量应为 %d, 实际: %d", len(expected), len(got))
+	}
+	for _, pos := range got {
+		if !expected[pos] {
+			t.Errorf("位置 %d 不应被置位", pos)
+		}
+	}
+
+	count := 0
+	bf.ForEachSetBit(func(pos int) { count++ })
+	if ones := int(bf.FillRatio()*float64(bf.size) + 0.5); count != ones {
+		t.Errorf("ForEachSetBit 应遍历 %d 个置位, 实际: %d", ones, count)
+	}
+}
+
+// TestHashUniformity 测试默认哈希分布均匀, 只映射到窄区间的哈希得分很低
+func TestHashUniformity(t *testing.T) {
+	good := NewBloomFilter(10000, 0.01)
+	bad := NewBloomFilterWithHasher(10000, 0.01, func(data []byte) (uint64, uint64) {
+		h1, h2 := HashPair(data)
+		return h1 % 100, h2 % 3
+	})
+	for i := 0; i < 2000; i++ {
+		key := []byte(fmt.Sprintf("key-%d", i))
+		good.Add(key)
+		bad.Add(key)
+		if !bad.Contains(key) {
+			t.Fatalf("自定义哈希函数的过滤器中 %s 应该存在", key)
+		}
+	}
+
+	goodScore, badScore := good.HashUniformity(), bad.HashUniformity()
+	t.Logf("默认哈希: %.4f, 窄区间哈希: %.4f", goodScore, badScore)
+	if goodScore < 0.5 {
+		t.Errorf("默认哈希应分布均匀, 得分: %.4f", goodScore)
+	}
+	if badScore > 0.05 {
+		t.Errorf("窄区间哈希应得分很低, 得分: %.4f", badScore)
+	}
+	if score := NewBloomFilter(100, 0.01).HashUniformity(); score != 1 {
+		t.Errorf("没有置位时应返回 1, 实际: %.4f", score)
+	}
+}
@@ -0,0 +1,58 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrIncompatible 表示两个过滤器的参数不同，位图不能直接合并
+var ErrIncompatible = errors.New("bloom filter: incompatible filters")
+
+// CompatibleWith 判断两个过滤器能否按位合并（Union/Intersect）
+// 要求位图大小、哈希函数数量和种子都相同，否则同一个元素在两个过滤器中对应不同的位，
+// 合并的结果没有意义。只有一方使用自定义哈希函数时不兼容；
+// 双方都使用自定义哈希函数时无法比较函数本身，需要调用方保证一致
+func (bf *BloomFilter) CompatibleWith(other *BloomFilter) bool {
+	return other != nil && bf.size == other.size && bf.hashCount == other.hashCount &&
+		bf.seedH1 == other.seedH1 && bf.seedH2 == other.seedH2 &&
+		(bf.hasher == nil) == (other.hasher == nil)
+}
+
+// checkCompatible 不兼容时返回说明差异的错误
+func (bf *BloomFilter) checkCompatible(other *BloomFilter) error {
+	if other == nil {
+		return fmt.Errorf("%w: other is nil", ErrIncompatible)
+	}
+	if !bf.CompatibleWith(other) {
+		// 种子只保存派生后的扰动值，未设置种子时为 0
+		return fmt.Errorf("%w: size %d/%d, hash count %d/%d, seed %#x/%#x, custom hasher %t/%t", ErrIncompatible,
+			bf.size, other.size, bf.hashCount, other.hashCount, bf.seedH1, other.seedH1,
+			bf.hasher != nil, other.hasher != nil)
+	}
+	return nil
+}
+
+// Union 将 other 合并到 bf，结果包含两个过滤器中的全部元素
+// 参数不兼容时返回 ErrIncompatible 且不修改 bf。不能与 Add 并发调用
+func (bf *BloomFilter) Union(other *BloomFilter) error {
+	if err := bf.checkCompatible(other); err != nil {
+		return err
+	}
+	for i, w := range other.bits {
+		bf.bits[i] |= w
+	}
+	return nil
+}
+
+// Intersect 将 bf 与 other 按位求交，结果近似于两个过滤器共同的元素
+// 同时存在于两者的元素一定仍然存在，但误判率高于直接用交集构建的过滤器。
+// 参数不兼容时返回 ErrIncompatible 且不修改 bf。不能与 Add 并发调用
+func (bf *BloomFilter) Intersect(other *BloomFilter) error {
+	if err := bf.checkCompatible(other); err != nil {
+		return err
+	}
+	for i, w := range other.bits {
+		bf.bits[i] &= w
+	}
+	return nil
+}
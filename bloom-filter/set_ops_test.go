@@ -0,0 +1,94 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestUnionIntersect 测试参数相同的过滤器可以合并
+func TestUnionIntersect(t *testing.T) {
+	a := NewBloomFilter(1000, 0.01)
+	b := NewBloomFilter(1000, 0.01)
+	a.Add([]byte("apple"))
+	a.Add([]byte("both"))
+	b.Add([]byte("banana"))
+	b.Add([]byte("both"))
+
+	if !a.CompatibleWith(b) {
+		t.Fatal("参数相同的过滤器应该兼容")
+	}
+
+	union := NewBloomFilter(1000, 0.01)
+	if err := union.Union(a); err != nil {
+		t.Fatalf("Union 失败: %v", err)
+	}
+	if err := union.Union(b); err != nil {
+		t.Fatalf("Union 失败: %v", err)
+	}
+	for _, key := range []string{"apple", "banana", "both"} {
+		if !union.Contains([]byte(key)) {
+			t.Errorf("并集中 %s 应该存在", key)
+		}
+	}
+
+	if err := a.Intersect(b); err != nil {
+		t.Fatalf("Intersect 失败: %v", err)
+	}
+	if !a.Contains([]byte("both")) {
+		t.Error("交集中 both 应该存在")
+	}
+	if a.Contains([]byte("apple")) || a.Contains([]byte("banana")) {
+		t.Error("交集中不应包含只在一个过滤器中的元素")
+	}
+}
+
+// TestIncompatible 测试大小、哈希函数数量、种子或是否使用自定义哈希函数不同的过滤器被拒绝
+func TestIncompatible(t *testing.T) {
+	base := NewBloomFilter(1000, 0.01)
+	moreHashes, err := NewBloomFilterK(1000, 0.01, base.HashCount()+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	others := map[string]*BloomFilter{
+		"size":      NewBloomFilter(2000, 0.01),
+		"hashCount": moreHashes,
+		"seed":      NewBloomFilterSeeded(1000, 0.01, 42),
+		"hasher":    NewBloomFilterWithHasher(1000, 0.01, HashPair),
+		"nil":       nil,
+	}
+
+	base.Add([]byte("x"))
+	for name, other := range others {
+		if base.CompatibleWith(other) {
+			t.Errorf("%s 不同的过滤器不应兼容", name)
+		}
+		if err := base.Union(other); !errors.Is(err, ErrIncompatible) {
+			t.Errorf("%s 不同时 Union 应返回 ErrIncompatible, 实际: %v", name, err)
+		}
+		if err := base.Intersect(other); !errors.Is(err, ErrIncompatible) {
+			t.Errorf("%s 不同时 Intersect 应返回 ErrIncompatible, 实际: %v", name, err)
+		}
+	}
+	if !base.Contains([]byte("x")) {
+		t.Error("合并失败时不应修改过滤器")
+	}
+}
+
+// TestIncompatibleMessage 测试只有种子或自定义哈希函数不同时，错误信息能看出差异
+func TestIncompatibleMessage(t *testing.T) {
+	base := NewBloomFilter(1000, 0.01)
+	seeded := NewBloomFilterSeeded(1000, 0.01, 42)
+
+	err := base.Union(seeded)
+	want := fmt.Sprintf("seed 0x0/%#x", seeded.seedH1)
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("种子不同时错误信息应包含 %q, 实际: %v", want, err)
+	}
+
+	err = base.Intersect(NewBloomFilterWithHasher(1000, 0.01, HashPair))
+	if err == nil || !strings.Contains(err.Error(), "custom hasher false/true") {
+		t.Errorf("只有一方使用自定义哈希函数时错误信息应说明, 实际: %v", err)
+	}
+}
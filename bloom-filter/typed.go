@@ -0,0 +1,54 @@
+package bloomfilter
+
+import "encoding/binary"
+
+// TypedBloomFilter 带类型的布隆过滤器
+// 通过构造时传入的编码函数将元素转换为 []byte，调用方无需自行转换
+type TypedBloomFilter[T any] struct {
+	filter *BloomFilter
+	encode func(T) []byte
+}
+
+// NewTypedBloomFilter 创建一个带类型的布隆过滤器
+// n: 预计插入的元素数量
+// p: 期望的误判率 (0 < p < 1)
+// encode: 元素编码函数，如 StringEncoder、IntegerEncoder
+func NewTypedBloomFilter[T any](n int, p float64, encode func(T) []byte) *TypedBloomFilter[T] {
+	return &TypedBloomFilter[T]{
+		filter: NewBloomFilter(n, p),
+		encode: encode,
+	}
+}
+
+// Add 添加元素
+func (tf *TypedBloomFilter[T]) Add(item T) {
+	tf.filter.Add(tf.encode(item))
+}
+
+// Contains 检查元素是否可能存在
+func (tf *TypedBloomFilter[T]) Contains(item T) bool {
+	return tf.filter.Contains(tf.encode(item))
+}
+
+// Filter 返回底层的布隆过滤器
+func (tf *TypedBloomFilter[T]) Filter() *BloomFilter {
+	return tf.filter
+}
+
+// Integer 所有整数类型
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// StringEncoder 字符串编码函数
+func StringEncoder(s string) []byte {
+	return []byte(s)
+}
+
+// IntegerEncoder 整数编码函数，按小端序编码为 8 字节
+func IntegerEncoder[T Integer](v T) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(v))
+	return buf
+}
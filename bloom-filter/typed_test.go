@@ -0,0 +1,45 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestTypedString 测试字符串类型的布隆过滤器
+func TestTypedString(t *testing.T) {
+	bf := NewTypedBloomFilter(1000, 0.01, StringEncoder)
+
+	for i := 0; i < 100; i++ {
+		bf.Add(fmt.Sprintf("user:%d", i))
+	}
+	for i := 0; i < 100; i++ {
+		if !bf.Contains(fmt.Sprintf("user:%d", i)) {
+			t.Fatalf("应该包含 user:%d", i)
+		}
+	}
+
+	// 与直接使用 []byte 的结果一致
+	if !bf.Filter().Contains([]byte("user:1")) {
+		t.Error("底层过滤器应该包含 user:1")
+	}
+}
+
+// TestTypedInteger 测试整数类型的布隆过滤器
+func TestTypedInteger(t *testing.T) {
+	bf := NewTypedBloomFilter(1000, 0.01, IntegerEncoder[int64])
+
+	for i := int64(0); i < 100; i++ {
+		bf.Add(i * 7)
+	}
+	for i := int64(0); i < 100; i++ {
+		if !bf.Contains(i * 7) {
+			t.Fatalf("应该包含 %d", i*7)
+		}
+	}
+
+	userIDs := NewTypedBloomFilter(100, 0.01, IntegerEncoder[uint32])
+	userIDs.Add(42)
+	if !userIDs.Contains(42) {
+		t.Error("应该包含 42")
+	}
+}
@@ -0,0 +1,116 @@
+package singleflight
+
+import (
+	"errors"
+	"runtime/debug"
+	"time"
+)
+
+// ErrNotReturned 表示 DoBatch 的 fn 没有返回某个请求的 key
+var ErrNotReturned = errors.New("singleflight: key not returned by batch fn")
+
+// DoBatch 一次合并多个 key 的调用
+// 已有调用进行中(或已缓存)的 key 直接等待并共享其结果, 其余 key 交给一次 fn 调用批量获取,
+// fn 的 missing 参数只包含这些 key。fn 的结果按 key 分发给所有等待该 key 的调用者,
+// 包括通过 Do 等待同一个 key 的调用者。
+// fn 返回错误时 missing 中的所有 key 都收到该错误;
+// fn 成功但返回的 map 中缺少某个 key 时, 该 key 收到 ErrNotReturned。
+// 返回值以调用方传入的 key 为键, 重复的 key 只计算一次。
+//
+// 批量的 fn 不经过单个 key 的执行路径, 以下 Group 配置对 DoBatch 新建的调用不生效:
+//   - WithTimeout: 批量 fn 不受执行时间上限约束, 也不会以 ErrTimeout 放弃
+//   - SlowCallThreshold/OnSlowCall: 批量 fn 执行再久也不触发慢调用告警
+//   - WithExternalGuard: 不获取跨进程锁
+//   - WithFairForget: 批量中的 key 被 Forget 时, DoBatch 的调用者直接收到 ErrForgotten, 不会重新开始
+//
+// 缓存(WithTTL/WithMaxEntries)、WithMaxInFlight、WithStats、RePanic 与 OnBurstError 照常生效
+func (g *Group) DoBatch(keys []string, fn func(missing []string) (map[string]interface{}, error)) map[string]Result {
+	results := make(map[string]Result, len(keys))
+	joined := make(map[string]*call)
+	owned := make(map[string]*call) // 原始 key -> 本次新建的 call
+	var missing []string
+
+	g.lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	for _, key := range keys {
+		if _, ok := results[key]; ok {
+			continue
+		}
+		if _, ok := joined[key]; ok {
+			continue
+		}
+		if _, ok := owned[key]; ok {
+			continue
+		}
+
+		mk := g.mapKey(key)
+		if g.closed {
+			results[key] = Result{nil, ErrClosed, false}
+			continue
+		}
+		if e, ok := g.lookupCache(mk); ok {
+			results[key] = Result{e.val, nil, true}
+			continue
+		}
+		if c, ok := g.m[mk]; ok {
+			c.join()
+			joined[key] = c
+			g.countShared()
+			continue
+		}
+		if g.full() {
+			results[key] = Result{nil, ErrTooManyInFlight, false}
+			continue
+		}
+
+		c := &call{key: key, startedAt: time.Now(), restarted: g.takeForgotten(mk), version: g.nextVersion()}
+		c.wg.Add(1)
+		g.running.Add(1)
+		g.m[mk] = c
+		owned[key] = c
+		missing = append(missing, key)
+	}
+	g.mu.Unlock()
+
+	if len(missing) > 0 {
+		g.countExecution()
+		fnErr := g.doBatchCall(missing, owned, fn)
+		for _, key := range missing {
+			c := owned[key]
+			results[key] = Result{c.val, c.err, false}
+		}
+		g.checkRePanic(fnErr)
+	}
+
+	for key, c := range joined {
+		c.wg.Wait()
+		results[key] = Result{c.val, c.err, true}
+	}
+	return results
+}
+
+// doBatchCall 执行批量 fn 并完成 owned 中的每个 call, 返回 fn 自身的错误
+func (g *Group) doBatchCall(missing []string, owned map[string]*call, fn func([]string) (map[string]interface{}, error)) (fnErr error) {
+	var vals map[string]interface{}
+	defer func() {
+		if r := recover(); r != nil {
+			vals, fnErr = nil, &PanicError{Value: r, Stack: debug.Stack()}
+		}
+
+		for _, key := range missing {
+			val, ok := vals[key]
+			err := fnErr
+			if err == nil && !ok {
+				err = ErrNotReturned
+			}
+			g.complete(owned[key], g.mapKey(key), val, err)
+			g.running.Done()
+		}
+	}()
+
+	// 不调用测试钩子 beforeExec, 见 DoBatch 中不生效的配置
+	vals, fnErr = fn(missing)
+	return fnErr
+}
@@ -0,0 +1,128 @@
+package singleflight
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestDoBatch 测试重叠的并发批量调用中每个 key 只获取一次
+func TestDoBatch(t *testing.T) {
+	var g Group
+	var mu sync.Mutex
+	fetched := make(map[string]int)
+	release := make(chan struct{})
+
+	fn := func(missing []string) (map[string]interface{}, error) {
+		mu.Lock()
+		for _, k := range missing {
+			fetched[k]++
+		}
+		mu.Unlock()
+		<-release
+
+		vals := make(map[string]interface{}, len(missing))
+		for _, k := range missing {
+			vals[k] = "v-" + k
+		}
+		return vals, nil
+	}
+
+	batches := [][]string{
+		{"a", "b", "c"},
+		{"b", "c", "d"},
+		{"c", "d", "e", "e"},
+	}
+	var wg sync.WaitGroup
+	results := make([]map[string]Result, len(batches))
+
+	// 第一个批次先占住 a、b、c, 后续批次只获取剩下的 key
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0] = g.DoBatch(batches[0], fn)
+	}()
+	waitInFlight(t, &g, 3)
+	for i := 1; i < len(batches); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = g.DoBatch(batches[i], fn)
+		}(i)
+		waitInFlight(t, &g, 3+i)
+	}
+	close(release)
+	wg.Wait()
+
+	for k, n := range fetched {
+		if n != 1 {
+			t.Errorf("key %s 期望获取 1 次, 实际 %d 次", k, n)
+		}
+	}
+	keys := make([]string, 0, len(fetched))
+	for k := range fetched {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if got := strings.Join(keys, ","); got != "a,b,c,d,e" {
+		t.Errorf("获取的 key 不正确: %s", got)
+	}
+
+	for i, batch := range batches {
+		for _, k := range batch {
+			res, ok := results[i][k]
+			if !ok || res.Err != nil || res.Val != "v-"+k {
+				t.Errorf("批次 %d 的 key %s 结果错误: %+v", i, k, res)
+			}
+		}
+	}
+	if !results[1]["b"].Shared || results[0]["b"].Shared {
+		t.Error("只有等待其他批次的 key 应标记为共享")
+	}
+}
+
+// TestDoBatchMissingKey 测试 fn 少返回 key 或返回错误时的结果
+func TestDoBatchMissingKey(t *testing.T) {
+	var g Group
+
+	res := g.DoBatch([]string{"a", "b"}, func(missing []string) (map[string]interface{}, error) {
+		return map[string]interface{}{"a": 1}, nil
+	})
+	if res["a"].Val != 1 || res["a"].Err != nil {
+		t.Errorf("key a 结果错误: %+v", res["a"])
+	}
+	if res["b"].Err != ErrNotReturned {
+		t.Errorf("key b 期望 ErrNotReturned, 实际: %+v", res["b"])
+	}
+
+	errFetch := errors.New("fetch failed")
+	res = g.DoBatch([]string{"a", "b"}, func(missing []string) (map[string]interface{}, error) {
+		return nil, errFetch
+	})
+	for _, k := range []string{"a", "b"} {
+		if res[k].Err != errFetch {
+			t.Errorf("key %s 期望收到 fn 的错误, 实际: %+v", k, res[k])
+		}
+	}
+}
+
+// waitInFlight 等待进行中的 key 数量达到 n
+func waitInFlight(t *testing.T, g *Group, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		g.mu.Lock()
+		count := len(g.m)
+		g.mu.Unlock()
+		if count == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("等待 %d 个进行中的 key 超时, 当前: %d", n, count)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,42 @@
+package singleflight
+
+import "time"
+
+// burstError 是窗口内汇总中的一种错误
+type burstError struct {
+	err   error // 窗口内第一次出现的错误值
+	count int   // 受影响的调用者数量
+}
+
+// recordBurstError 将一次失败的调用计入 err 的汇总, 窗口内第一次出现时开始计时
+// 调用方需持有 g.mu
+func (g *Group) recordBurstError(err error, count int) {
+	if g.BurstErrorWindow <= 0 || g.OnBurstError == nil {
+		return
+	}
+
+	msg := err.Error()
+	if b, ok := g.bursts[msg]; ok {
+		b.count += count
+		return
+	}
+	if g.bursts == nil {
+		g.bursts = make(map[string]*burstError)
+	}
+	g.bursts[msg] = &burstError{err: err, count: count}
+	time.AfterFunc(g.BurstErrorWindow, func() {
+		g.flushBurstError(msg)
+	})
+}
+
+// flushBurstError 窗口结束时触发 err 的汇总回调
+func (g *Group) flushBurstError(msg string) {
+	g.lock()
+	b := g.bursts[msg]
+	delete(g.bursts, msg)
+	g.mu.Unlock()
+
+	if b != nil {
+		g.OnBurstError(b.err, b.count)
+	}
+}
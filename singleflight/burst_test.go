@@ -0,0 +1,65 @@
+package singleflight
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestOnBurstError 测试窗口内相同的错误只汇总触发一次回调
+func TestOnBurstError(t *testing.T) {
+	type burst struct {
+		msg   string
+		count int
+	}
+	fired := make(chan burst, 10)
+	g := &Group{
+		BurstErrorWindow: 200 * time.Millisecond,
+		OnBurstError: func(err error, count int) {
+			fired <- burst{err.Error(), count}
+		},
+	}
+
+	// 20 个 key 返回内容相同但不是同一个值的错误, 5 个 key 返回另一种错误
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			g.Do(fmt.Sprintf("user:%d", i), func() (interface{}, error) {
+				return nil, errors.New("db down")
+			})
+		}(i)
+	}
+	for i := 0; i < 5; i++ {
+		g.Do(fmt.Sprintf("order:%d", i), func() (interface{}, error) {
+			return nil, errors.New("timeout")
+		})
+	}
+	g.Do("ok", func() (interface{}, error) { return "ok", nil })
+	wg.Wait()
+
+	got := make(map[string]int)
+	for i := 0; i < 2; i++ {
+		select {
+		case b := <-fired:
+			if _, dup := got[b.msg]; dup {
+				t.Errorf("%q 应只触发一次回调", b.msg)
+			}
+			got[b.msg] = b.count
+		case <-time.After(time.Second):
+			t.Fatal("等待回调超时")
+		}
+	}
+	if got["db down"] != 20 || got["timeout"] != 5 {
+		t.Errorf("期望 db down 20 次、timeout 5 次, 实际: %v", got)
+	}
+
+	select {
+	case b := <-fired:
+		t.Errorf("不应再触发回调, 实际: %+v", b)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,132 @@
+package singleflight
+
+import (
+	"container/list"
+	"time"
+)
+
+// cacheEntry 是 TTL 缓存中一次已完成调用的结果
+type cacheEntry struct {
+	key     string // 调用方传入的原始 key
+	val     interface{}
+	expires time.Time     // 过期时间
+	version uint64        // 产生该结果的执行的版本号
+	elem    *list.Element // 在 g.cacheLRU 中的位置
+}
+
+// NewGroupWithTTL 创建一个缓存已完成结果的 Group
+// fn 成功返回后结果保留 ttl,期间相同 key 的调用直接返回缓存结果而不再执行 fn;
+// 返回错误的调用不缓存,下一次调用会重新执行
+func NewGroupWithTTL(ttl time.Duration) *Group {
+	return NewGroup(WithTTL(ttl))
+}
+
+// NewGroupWithCache 创建一个缓存成功结果并限制缓存数量的 Group
+// 结果保留 ttl, 缓存的结果超过 maxEntries 个时淘汰最久未使用的;
+// 返回错误的调用不缓存。调用先查缓存, 未命中再合并进行中的调用, 最后执行 fn,
+// 一个对象同时解决缓存击穿和内存无限增长的问题
+func NewGroupWithCache(maxEntries int, ttl time.Duration) *Group {
+	return NewGroup(WithTTL(ttl), WithMaxEntries(maxEntries))
+}
+
+// lookupCache 返回 key 未过期的缓存结果, 过期的条目顺便清除
+// 调用方需持有 g.mu
+func (g *Group) lookupCache(key string) (*cacheEntry, bool) {
+	e, ok := g.cache[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		g.deleteCache(key)
+		return nil, false
+	}
+	g.cacheLRU.MoveToFront(e.elem)
+	return e, true
+}
+
+// deleteCache 删除 key 的缓存结果
+// 调用方需持有 g.mu
+func (g *Group) deleteCache(key string) {
+	if e, ok := g.cache[key]; ok {
+		g.cacheLRU.Remove(e.elem)
+		delete(g.cache, key)
+	}
+}
+
+// storeCache 在开启 TTL 缓存(或调用指定了缓存时间)时保存 c 的成功结果
+// 调用方需持有 g.mu
+func (g *Group) storeCache(key string, c *call) {
+	ttl := c.ttl
+	if ttl == 0 {
+		ttl = g.cacheTTL
+	}
+	if ttl <= 0 || c.err != nil {
+		return
+	}
+	if g.cache == nil {
+		g.cache = make(map[string]*cacheEntry)
+		g.cacheLRU = list.New()
+	}
+	g.deleteCache(key)
+	e := &cacheEntry{key: c.key, val: c.val, expires: time.Now().Add(ttl), version: c.version}
+	e.elem = g.cacheLRU.PushFront(key)
+	g.cache[key] = e
+
+	if g.cacheMax > 0 && g.cacheLRU.Len() > g.cacheMax {
+		g.deleteCache(g.cacheLRU.Back().Value.(string))
+	}
+}
+
+// WeightedTTLFactor 是 DoWeighted 中缓存时间与成本的比例
+const WeightedTTLFactor = 10
+
+// DoWeighted 类似于 Do,但成功的结果按成本缓存, 成本越高缓存越久
+// cost 是调用方对 fn 执行成本的估计(如平均耗时), 结果缓存 cost * WeightedTTLFactor:
+// 每个 key 重新执行所占的时间不超过约 1/(1+WeightedTTLFactor),
+// 廉价的结果很快过期、保持新鲜, 昂贵的结果被更长时间复用。
+// 不需要 Group 开启 TTL 缓存; cost <= 0 时不缓存
+func (g *Group) DoWeighted(key string, cost time.Duration, fn func() (interface{}, error)) (interface{}, error) {
+	ttl := cost * WeightedTTLFactor
+	if ttl <= 0 {
+		// 负值表示不缓存, 避免回落到 Group 的缓存时间
+		ttl = -1
+	}
+	c, _ := g.doTTL(key, ttl, fn)
+	return c.val, c.err
+}
+
+// ForgetFunc 清除缓存结果中满足 pred 的条目,使相应 key 的下一次调用重新执行 fn
+// 适合写入后按值精确失效旧结果。
+// 只作用于 TTL 缓存中已完成的结果,正在执行的调用不受影响。
+// pred 在内部锁之外对调用时缓存的快照执行,可以回调 Group 的其他方法;
+// 判定期间被刷新的条目不会被清除,新结果以新的值为准
+func (g *Group) ForgetFunc(pred func(key string, val interface{}) bool) {
+	type entry struct {
+		k string
+		e *cacheEntry
+	}
+	g.lock()
+	entries := make([]entry, 0, len(g.cache))
+	for k, e := range g.cache {
+		entries = append(entries, entry{k, e})
+	}
+	g.mu.Unlock()
+
+	var matched []entry
+	for _, en := range entries {
+		if pred(en.e.key, en.e.val) {
+			matched = append(matched, en)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	g.lock()
+	defer g.mu.Unlock()
+	for _, en := range matched {
+		if g.cache[en.k] == en.e {
+			g.deleteCache(en.k)
+		}
+	}
+}
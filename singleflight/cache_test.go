@@ -0,0 +1,155 @@
+package singleflight
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestForgetFunc 测试按值清除部分缓存结果
+func TestForgetFunc(t *testing.T) {
+	g := NewGroupWithTTL(time.Minute)
+	calls := make(map[string]int)
+	fn := func(key string, val int) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			calls[key]++
+			return val, nil
+		}
+	}
+
+	values := map[string]int{"a": 1, "b": 2, "c": 3, "d": 4}
+	for k, v := range values {
+		g.Do(k, fn(k, v))
+	}
+
+	// 缓存期内不再执行 fn
+	for k, v := range values {
+		val, _ := g.Do(k, fn(k, v))
+		if val != v {
+			t.Errorf("key %s 期望缓存值 %d, 实际: %v", k, v, val)
+		}
+	}
+	for k := range values {
+		if calls[k] != 1 {
+			t.Errorf("key %s 缓存期内期望执行 1 次, 实际 %d 次", k, calls[k])
+		}
+	}
+
+	// 清除值为偶数的结果
+	g.ForgetFunc(func(key string, val interface{}) bool {
+		return val.(int)%2 == 0
+	})
+
+	for k, v := range values {
+		g.Do(k, fn(k, v))
+	}
+	want := map[string]int{"a": 1, "b": 2, "c": 1, "d": 2}
+	for k, n := range want {
+		if calls[k] != n {
+			t.Errorf("key %s 期望执行 %d 次, 实际 %d 次", k, n, calls[k])
+		}
+	}
+}
+
+// TestDoWeighted 测试高成本的结果被复用而低成本的结果重新执行
+func TestDoWeighted(t *testing.T) {
+	var g Group
+	calls := make(map[string]int)
+	fn := func(key string) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			calls[key]++
+			return key, nil
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		g.DoWeighted("expensive", time.Second, fn("expensive"))
+		g.DoWeighted("cheap", time.Microsecond, fn("cheap"))
+		time.Sleep(time.Millisecond)
+	}
+
+	if calls["expensive"] != 1 {
+		t.Errorf("高成本的结果应被复用, 期望执行 1 次, 实际 %d 次", calls["expensive"])
+	}
+	if calls["cheap"] != 3 {
+		t.Errorf("低成本的结果应很快过期, 期望执行 3 次, 实际 %d 次", calls["cheap"])
+	}
+}
+
+// TestNewGroupWithCache 测试缓存命中跳过 fn, 超过容量时淘汰最久未使用的结果
+func TestNewGroupWithCache(t *testing.T) {
+	g := NewGroupWithCache(2, time.Minute)
+	calls := make(map[string]int)
+	fn := func(key string) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			calls[key]++
+			return key, nil
+		}
+	}
+
+	g.Do("a", fn("a"))
+	g.Do("b", fn("b"))
+	if val, _ := g.Do("a", fn("a")); val != "a" || calls["a"] != 1 {
+		t.Errorf("命中缓存应跳过 fn, 结果: %v, 执行次数: %d", val, calls["a"])
+	}
+
+	// a 刚被访问, 加入 c 时淘汰最久未使用的 b
+	g.Do("c", fn("c"))
+	g.Do("a", fn("a"))
+	g.Do("b", fn("b"))
+	if calls["a"] != 1 {
+		t.Errorf("a 不应被淘汰, 执行次数: %d", calls["a"])
+	}
+	if calls["b"] != 2 {
+		t.Errorf("b 应被淘汰后重新执行, 执行次数: %d", calls["b"])
+	}
+	if n := len(g.cache); n != 2 {
+		t.Errorf("缓存结果数量应为 2, 实际: %d", n)
+	}
+
+	// 错误不缓存
+	errFn := func() (interface{}, error) {
+		calls["err"]++
+		return nil, errors.New("boom")
+	}
+	g.Do("err", errFn)
+	g.Do("err", errFn)
+	if calls["err"] != 2 {
+		t.Errorf("错误结果不应缓存, 执行次数: %d", calls["err"])
+	}
+}
+
+// TestForgetFuncReentrant 测试 pred 回调 Group 的其他方法时不会死锁
+func TestForgetFuncReentrant(t *testing.T) {
+	g := NewGroupWithTTL(time.Minute)
+	g.Do("a", func() (interface{}, error) { return 1, nil })
+	g.Do("b", func() (interface{}, error) { return 2, nil })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.ForgetFunc(func(key string, val interface{}) bool {
+			g.Stats()
+			if key == "b" {
+				g.Forget("a")
+			}
+			return key == "b"
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("pred 回调 Group 时 ForgetFunc 不应死锁")
+	}
+
+	calls := 0
+	for _, key := range []string{"a", "b"} {
+		g.Do(key, func() (interface{}, error) {
+			calls++
+			return 0, nil
+		})
+	}
+	if calls != 2 {
+		t.Errorf("a 被 Forget、b 被 ForgetFunc 清除, 两者都应重新执行, 实际执行 %d 次", calls)
+	}
+}
@@ -0,0 +1,44 @@
+package singleflight
+
+import "context"
+
+// DoCancelable 类似于 DoDetached,但 fn 收到一个在所有调用者都离开后才取消的 context
+// 每个调用者可以通过自己的 ctx 提前离开; 只要还有调用者在等待, fn 的 context 就不会取消,
+// 全部离开后 fn 的 context 被取消, fn 可以据此提前结束, 避免为无人等待的调用浪费后端资源。
+// 通过 Do、DoChan 等方法加入同一个调用的等待者无法离开, 有它们在时 fn 的 context 不会因此取消。
+// fn 的 context 不继承任何调用者的 ctx, 调用完成后也会被取消
+func (g *Group) DoCancelable(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (interface{}, error) {
+	fnCtx, cancel := context.WithCancel(context.Background())
+	ch := make(chan Result, 1)
+	c, created := g.doChan(key, waiter{ch: ch, close: true}, cancel, func() (interface{}, error) {
+		return fn(fnCtx)
+	})
+	if !created {
+		// 没有新建调用, fnCtx 不会被使用
+		cancel()
+	}
+
+	select {
+	case res := <-ch:
+		g.completedWaiters.Add(1)
+		return res.Val, res.Err
+	case <-ctx.Done():
+		g.canceledWaiters.Add(1)
+		if c != nil {
+			g.leave(c)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// leave 记录一个调用者离开 c, 最后一个调用者离开时取消 fn 的 context
+func (g *Group) leave(c *call) {
+	g.lock()
+	c.live--
+	last := c.live == 0
+	g.mu.Unlock()
+
+	if last && c.cancel != nil {
+		c.cancel()
+	}
+}
@@ -0,0 +1,106 @@
+package singleflight
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestDoCancelableAllLeave 测试所有调用者离开后 fn 的 context 被取消
+func TestDoCancelableAllLeave(t *testing.T) {
+	var g Group
+	fnCanceled := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		close(fnCanceled)
+		return nil, ctx.Err()
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err := g.DoCancelable(ctx1, "key", fn)
+		errs <- err
+	}()
+	waitInFlight(t, &g, 1)
+	go func() {
+		_, err := g.DoCancelable(ctx2, "key", fn)
+		errs <- err
+	}()
+	waitDups(t, &g, "key", 1)
+
+	cancel1()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("离开的调用者期望收到 context.Canceled, 实际: %v", err)
+	}
+	select {
+	case <-fnCanceled:
+		t.Fatal("还有调用者在等待, fn 的 context 不应被取消")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel2()
+	<-errs
+	select {
+	case <-fnCanceled:
+	case <-time.After(time.Second):
+		t.Fatal("所有调用者离开后 fn 的 context 应被取消")
+	}
+}
+
+// TestDoCancelableWaiterRemains 测试仍有调用者等待时 fn 正常完成
+func TestDoCancelableWaiterRemains(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		select {
+		case <-release:
+			return "result", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	left := make(chan error, 1)
+	go func() {
+		_, err := g.DoCancelable(ctx1, "key", fn)
+		left <- err
+	}()
+	waitInFlight(t, &g, 1)
+
+	result := make(chan interface{}, 1)
+	go func() {
+		val, _ := g.DoCancelable(context.Background(), "key", fn)
+		result <- val
+	}()
+	waitDups(t, &g, "key", 1)
+
+	cancel1()
+	<-left
+	close(release)
+
+	if val := <-result; val != "result" {
+		t.Errorf("留下的调用者应收到 fn 的结果, 实际: %v", val)
+	}
+}
+
+// waitDups 等待 key 的调用有 n 个等待者加入
+func waitDups(t *testing.T, g *Group, key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		g.mu.Lock()
+		c, ok := g.m[key]
+		joined := ok && c.dups >= n
+		g.mu.Unlock()
+		if joined {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("等待 %d 个等待者加入超时", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
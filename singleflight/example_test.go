@@ -192,21 +192,26 @@ func BenchmarkConcurrent(b *testing.B) {
 }
 
 // TestConcurrency 并发安全性测试
+// 执行者等到其余 99 个调用者都加入后才开始执行 fn, 不依赖 time.Sleep
 func TestConcurrency(t *testing.T) {
 	var g Group
+	holdUntilJoined(&g, map[string]int{"test-key": 99})
 	var wg sync.WaitGroup
 	var counter int32
+	var waiters int32
 
 	// 100 个并发请求
 	for i := 0; i < 100; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			_, _ = g.Do("test-key", func() (interface{}, error) {
+			res := g.DoDetailed("test-key", func() (interface{}, error) {
 				atomic.AddInt32(&counter, 1)
-				time.Sleep(10 * time.Millisecond)
 				return "result", nil
 			})
+			if res.Role == RoleWaiter {
+				atomic.AddInt32(&waiters, 1)
+			}
 		}()
 	}
 
@@ -215,6 +220,9 @@ func TestConcurrency(t *testing.T) {
 	if counter != 1 {
 		t.Errorf("期望执行 1 次,实际执行 %d 次", counter)
 	}
+	if waiters != 99 {
+		t.Errorf("期望 99 个等待者共享结果,实际 %d 个", waiters)
+	}
 }
 
 // TestMultipleKeys 测试多个 key
@@ -223,15 +231,18 @@ func TestMultipleKeys(t *testing.T) {
 	var wg sync.WaitGroup
 	mu := sync.Mutex{}
 	results := make(map[string]int)
+	var executions int32
 
 	keys := []string{"a", "b", "c", "a", "b", "a", "c", "b", "a"}
+	// 每个 key 的执行者等到其余调用者都加入后才开始执行
+	holdUntilJoined(&g, map[string]int{"a": 3, "b": 2, "c": 1})
 
 	for _, key := range keys {
 		wg.Add(1)
 		go func(k string) {
 			defer wg.Done()
 			g.Do(k, func() (interface{}, error) {
-				time.Sleep(50 * time.Millisecond)
+				atomic.AddInt32(&executions, 1)
 				return k, nil
 			})
 
@@ -249,4 +260,7 @@ func TestMultipleKeys(t *testing.T) {
 			t.Errorf("key %s: 期望 %d 次调用,实际 %d 次", k, v, results[k])
 		}
 	}
+	if executions != 3 {
+		t.Errorf("每个 key 应只执行 1 次,实际共执行 %d 次", executions)
+	}
 }
@@ -0,0 +1,20 @@
+package singleflight
+
+// ExternalGuard 是执行 fn 前获取的外部锁, 把合并调用的范围从单个进程扩展到整台主机或集群
+// 例如基于共享内存的建议锁、按 key 命名的文件锁, 不需要依赖 Redis。
+// 进程内仍由 Group 合并调用, 只有执行者会获取外部锁; 其他进程中同一 key 的执行者
+// 在锁释放前阻塞, 因此 fn 应在获取锁之后先检查外部缓存, 命中时直接返回(双重检查),
+// 才能避免各进程依次重复回源
+type ExternalGuard interface {
+	// Acquire 获取 key 的外部锁, 阻塞直到获取成功, 返回释放锁的函数
+	// 返回错误时不执行 fn, 所有调用者收到该错误
+	Acquire(key string) (release func(), err error)
+}
+
+// WithExternalGuard 设置执行 fn 前获取的外部锁, 见 ExternalGuard
+// 默认不获取外部锁。只作用于单个 key 的调用, DoBatch 不获取外部锁
+func WithExternalGuard(guard ExternalGuard) Option {
+	return func(g *Group) {
+		g.guard = guard
+	}
+}
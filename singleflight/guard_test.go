@@ -0,0 +1,105 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mockGuard 模拟跨进程的按 key 加锁
+type mockGuard struct {
+	mu       sync.Mutex
+	locks    map[string]*sync.Mutex
+	acquires atomic.Int32
+	err      error
+}
+
+func newMockGuard() *mockGuard {
+	return &mockGuard{locks: make(map[string]*sync.Mutex)}
+}
+
+func (m *mockGuard) lock(key string) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		m.locks[key] = l
+	}
+	return l
+}
+
+func (m *mockGuard) Acquire(key string) (func(), error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	l := m.lock(key)
+	l.Lock()
+	m.acquires.Add(1)
+	return l.Unlock, nil
+}
+
+// TestExternalGuard 测试 fn 在外部锁释放前不会执行, 两个 Group(模拟两个进程)共享同一把锁
+func TestExternalGuard(t *testing.T) {
+	guard := newMockGuard()
+	g1 := NewGroup(WithExternalGuard(guard))
+	g2 := NewGroup(WithExternalGuard(guard))
+
+	// 另一个进程持有 key 的锁
+	external := guard.lock("key")
+	external.Lock()
+
+	var running, maxRunning atomic.Int32
+	fn := func() (interface{}, error) {
+		n := running.Add(1)
+		if n > maxRunning.Load() {
+			maxRunning.Store(n)
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		return "ok", nil
+	}
+
+	var wg sync.WaitGroup
+	for _, g := range []*Group{g1, g1, g2, g2} {
+		wg.Add(1)
+		go func(g *Group) {
+			defer wg.Done()
+			if val, err := g.Do("key", fn); err != nil || val != "ok" {
+				t.Errorf("期望 ok, 实际: %v, %v", val, err)
+			}
+		}(g)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if n := running.Load(); n != 0 {
+		t.Fatalf("外部锁释放前 fn 不应执行, 正在执行: %d", n)
+	}
+	external.Unlock()
+	wg.Wait()
+
+	if n := maxRunning.Load(); n != 1 {
+		t.Errorf("同一时刻应只有一个进程执行 fn, 实际最多: %d", n)
+	}
+	if n := guard.acquires.Load(); n < 2 || n > 4 {
+		t.Errorf("每个进程的执行者各获取一次锁, 实际: %d", n)
+	}
+}
+
+// TestExternalGuardError 测试获取外部锁失败时不执行 fn
+func TestExternalGuardError(t *testing.T) {
+	guard := newMockGuard()
+	guard.err = errors.New("lock unavailable")
+	g := NewGroup(WithExternalGuard(guard))
+
+	called := false
+	_, err := g.Do("key", func() (interface{}, error) {
+		called = true
+		return nil, nil
+	})
+	if err != guard.err || called {
+		t.Errorf("获取外部锁失败时应返回该错误且不执行 fn, 实际: %v, 执行: %v", err, called)
+	}
+}
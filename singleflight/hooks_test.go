@@ -0,0 +1,51 @@
+package singleflight
+
+import (
+	"sync"
+	"testing"
+)
+
+// holdUntilJoined 设置测试钩子, 让每个 key 的执行者在 waiters[key] 个等待者加入后才开始执行 fn
+// 不依赖 time.Sleep 制造重叠窗口, 并发场景是确定的
+func holdUntilJoined(g *Group, waiters map[string]int) {
+	joined := make(map[string]*sync.WaitGroup, len(waiters))
+	for key, n := range waiters {
+		joined[key] = new(sync.WaitGroup)
+		joined[key].Add(n)
+	}
+	g.onJoin = func(key string) {
+		joined[key].Done()
+	}
+	g.beforeExec = func(key string) {
+		joined[key].Wait()
+	}
+}
+
+// TestHoldUntilJoinedDoChan 测试测试钩子同样适用于 DoChan
+func TestHoldUntilJoinedDoChan(t *testing.T) {
+	var g Group
+	holdUntilJoined(&g, map[string]int{"key": 4})
+
+	var calls int
+	chans := make([]<-chan Result, 5)
+	for i := range chans {
+		chans[i] = g.DoChan("key", func() (interface{}, error) {
+			calls++
+			return "ok", nil
+		})
+	}
+
+	shared := 0
+	for _, ch := range chans {
+		res := <-ch
+		if res.Val != "ok" || res.Err != nil {
+			t.Errorf("期望结果 ok, 实际: %v, %v", res.Val, res.Err)
+		}
+		if res.Shared {
+			shared++
+		}
+	}
+	if calls != 1 || shared != 4 {
+		t.Errorf("期望执行 1 次且 4 个等待者的结果为共享, 实际执行 %d 次, 共享 %d 个", calls, shared)
+	}
+}
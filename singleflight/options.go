@@ -0,0 +1,98 @@
+package singleflight
+
+import "time"
+
+// Option 配置 Group 的行为, 用于 NewGroup
+type Option func(*Group)
+
+// NewGroup 创建一个按 opts 配置的 Group
+// 不传任何选项时与零值 Group{} 的行为相同
+func NewGroup(opts ...Option) *Group {
+	g := &Group{}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// WithTimeout 设置 fn 的默认执行时间上限
+// fn 超过 d 仍未返回时, 执行者和所有等待者都收到 ErrTimeout, key 随之清除;
+// fn 无法被中断, 会在后台执行完毕, 但其结果(包括 panic)被丢弃。
+// 设置后 Do 系列方法的 fn 总是在独立的 goroutine 中执行
+func WithTimeout(d time.Duration) Option {
+	return func(g *Group) {
+		g.timeout = d
+	}
+}
+
+// WithMaxInFlight 限制同时进行中的不同 key 数量, 见 NewGroupWithLimit
+func WithMaxInFlight(n int) Option {
+	return func(g *Group) {
+		g.maxInFlight = n
+	}
+}
+
+// WithPanicRecovery 设置 fn 发生 panic 时的处理方式
+// enabled 为 true(默认)时 panic 转换为 *PanicError 返回给所有调用者;
+// 为 false 时通知所有调用者后在执行者中重新抛出, 等同于设置 RePanic
+func WithPanicRecovery(enabled bool) Option {
+	return func(g *Group) {
+		g.RePanic = !enabled
+	}
+}
+
+// WithStats 开启 fn 执行次数和共享次数的统计, 结果见 Stats
+// 默认不统计, 避免每次调用额外的原子操作
+func WithStats() Option {
+	return func(g *Group) {
+		g.statsEnabled = true
+	}
+}
+
+// WithLockStats 统计 goroutine 等待内部锁的累计时间和最长时间, 结果见 Stats
+// 所有 key 共用一把锁, 并发很高时锁竞争可能成为瓶颈, 可据此判断是否需要按 key 分片。
+// 默认不统计, 避免每次加锁额外读取两次时间
+func WithLockStats() Option {
+	return func(g *Group) {
+		g.lockStats = true
+	}
+}
+
+// WithKeyHash 对 key 做哈希后再合并调用, 见 NewHashedGroup
+// hashFn 为 nil 时使用 FNVHash
+func WithKeyHash(hashFn func(string) uint64) Option {
+	return func(g *Group) {
+		if hashFn == nil {
+			hashFn = FNVHash
+		}
+		g.hashKey = hashFn
+	}
+}
+
+// WithTTL 缓存成功的结果 ttl, 见 NewGroupWithTTL
+func WithTTL(ttl time.Duration) Option {
+	return func(g *Group) {
+		g.cacheTTL = ttl
+	}
+}
+
+// WithMaxEntries 限制缓存结果的数量, 超出时淘汰最久未使用的结果, 见 NewGroupWithCache
+// 需要同时通过 WithTTL 开启缓存
+func WithMaxEntries(n int) Option {
+	return func(g *Group) {
+		g.cacheMax = n
+	}
+}
+
+// WithFairForget 避免 Forget 频繁时早到的调用者反复被打断、迟迟拿不到结果
+// 开启后通过 Do 系列方法等待的调用者(包括执行者)在 Forget 时不立即收到 ErrForgotten,
+// 而是重新加入该 key 的下一次执行。一个调用者最多重新开始 maxRestarts 次:
+// 其所在调用的调用者中有人已重新开始 maxRestarts 次时, Forget 只把该调用从 Group 中摘除,
+// 之后的调用者重新执行 fn, 而已在等待的调用者仍收到该调用的结果(不写入缓存)。
+// 因此每个调用者最多等待 maxRestarts+1 次 fn 执行就会拿到结果。
+// DoChan 系列方法的调用者不受影响, Forget 时仍收到 ErrForgotten
+func WithFairForget(maxRestarts int) Option {
+	return func(g *Group) {
+		g.fairForget = maxRestarts
+	}
+}
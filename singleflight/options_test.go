@@ -0,0 +1,205 @@
+package singleflight
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestNewGroupOptions 测试选项被正确应用
+func TestNewGroupOptions(t *testing.T) {
+	g := NewGroup(
+		WithTimeout(time.Second),
+		WithMaxInFlight(3),
+		WithPanicRecovery(false),
+		WithStats(),
+		WithKeyHash(nil),
+		WithTTL(time.Minute),
+	)
+	if g.timeout != time.Second || g.maxInFlight != 3 || !g.RePanic ||
+		!g.statsEnabled || g.hashKey == nil || g.cacheTTL != time.Minute {
+		t.Errorf("选项未被正确应用: %+v", g)
+	}
+
+	if g := NewGroupWithLimit(5); g.maxInFlight != 5 {
+		t.Errorf("NewGroupWithLimit 应设置进行中 key 的上限, 实际: %d", g.maxInFlight)
+	}
+}
+
+// TestWithTimeout 测试 fn 超时后执行者和等待者都收到 ErrTimeout
+func TestWithTimeout(t *testing.T) {
+	g := NewGroup(WithTimeout(20 * time.Millisecond))
+	release := make(chan struct{})
+	defer close(release)
+	fn := func() (interface{}, error) {
+		<-release
+		return "late", nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := g.Do("key", fn)
+			errs <- err
+		}()
+	}
+	res := <-g.DoChan("key", fn)
+	errs <- res.Err
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != ErrTimeout {
+			t.Errorf("期望 ErrTimeout, 实际: %v", err)
+		}
+	}
+
+	// 超时后 key 已清除, 新的调用重新执行
+	val, err := g.Do("key", func() (interface{}, error) {
+		return "fresh", nil
+	})
+	if err != nil || val != "fresh" {
+		t.Errorf("超时后的调用应重新执行, 实际: %v, %v", val, err)
+	}
+}
+
+// TestWithStats 测试开启统计后记录执行与共享次数, 零值 Group 不统计
+func TestWithStats(t *testing.T) {
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return "result", nil
+	}
+	run := func(g *Group) {
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				g.Do("key", fn)
+			}()
+		}
+		// 等待所有调用者加入
+		for {
+			g.mu.Lock()
+			c, ok := g.m["key"]
+			joined := ok && c.dups == 4
+			g.mu.Unlock()
+			if joined {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		release <- struct{}{}
+		wg.Wait()
+	}
+
+	g := NewGroup(WithStats())
+	run(g)
+	if s := g.Stats(); s.Executions != 1 || s.SharedCalls != 4 {
+		t.Errorf("期望执行 1 次、共享 4 次, 实际: %+v", s)
+	}
+
+	var zero Group
+	run(&zero)
+	if s := zero.Stats(); s.Executions != 0 || s.SharedCalls != 0 {
+		t.Errorf("零值 Group 不应统计, 实际: %+v", s)
+	}
+}
+
+// TestWithLockStats 测试锁竞争下记录到非零的等待时间
+func TestWithLockStats(t *testing.T) {
+	g := NewGroup(WithLockStats())
+
+	// 人为持有锁, 让其他 goroutine 等待
+	g.mu.Lock()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.Do("key", func() (interface{}, error) {
+				return nil, nil
+			})
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	g.mu.Unlock()
+	wg.Wait()
+
+	s := g.Stats()
+	if s.LockWaitTotal < 20*time.Millisecond || s.LockWaitMax < 10*time.Millisecond {
+		t.Errorf("锁等待时间应不小于人为持有锁的时间, 实际: total=%v max=%v", s.LockWaitTotal, s.LockWaitMax)
+	}
+	if s.LockWaitMax > s.LockWaitTotal {
+		t.Errorf("最长等待时间不应超过累计时间: max=%v total=%v", s.LockWaitMax, s.LockWaitTotal)
+	}
+
+	var zero Group
+	zero.Do("key", func() (interface{}, error) { return nil, nil })
+	if s := zero.Stats(); s.LockWaitTotal != 0 {
+		t.Errorf("未开启时不应统计, 实际: %v", s.LockWaitTotal)
+	}
+}
+
+// TestWithFairForget 测试 Forget 频繁时早到的调用者仍能在有限次重新开始后拿到结果
+func TestWithFairForget(t *testing.T) {
+	g := NewGroup(WithFairForget(2))
+	var executions atomic.Int32
+	fn := func() (interface{}, error) {
+		executions.Add(1)
+		time.Sleep(time.Millisecond)
+		return "ok", nil
+	}
+
+	stop := make(chan struct{})
+	var hammer sync.WaitGroup
+	hammer.Add(1)
+	go func() {
+		defer hammer.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				g.Forget("key")
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	type result struct {
+		val interface{}
+		err error
+	}
+	done := make(chan result, 4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			val, err := g.Do("key", fn)
+			done <- result{val, err}
+		}()
+	}
+
+	for i := 0; i < 4; i++ {
+		select {
+		case res := <-done:
+			if res.err != nil || res.val != "ok" {
+				t.Errorf("调用者应在有限次重新开始后拿到结果, 实际: %v, %v", res.val, res.err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Forget 频繁时调用者迟迟拿不到结果")
+		}
+	}
+	close(stop)
+	hammer.Wait()
+
+	// 每个调用者最多等待 3 次执行
+	if n := executions.Load(); n > 4*3 {
+		t.Errorf("fn 执行次数应不超过 12, 实际: %d", n)
+	}
+}
@@ -0,0 +1,31 @@
+package singleflight
+
+// Role 表示调用者在一次调用中的角色
+type Role int
+
+const (
+	RoleRejected Role = iota // 调用被拒绝(ErrClosed、ErrTooManyInFlight), 既没有执行 fn 也没有共享结果
+	RolePrimary              // 执行者: 执行了 fn
+	RoleWaiter               // 等待者: 加入了进行中的调用, 共享执行者的结果
+	RoleCacheHit             // 缓存命中: 直接返回 TTL 缓存中的结果, 没有执行 fn
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleRejected:
+		return "rejected"
+	case RolePrimary:
+		return "primary"
+	case RoleWaiter:
+		return "waiter"
+	case RoleCacheHit:
+		return "cache-hit"
+	default:
+		return "unknown"
+	}
+}
+
+// shared 判断该角色拿到的结果是否是共享的, 即不是自己执行 fn 得到的
+func (r Role) shared() bool {
+	return r == RoleWaiter || r == RoleCacheHit
+}
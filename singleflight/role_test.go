@@ -0,0 +1,107 @@
+package singleflight
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRole 测试并发与先后调用时角色的分配
+func TestRole(t *testing.T) {
+	g := NewGroupWithTTL(time.Minute)
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return "ok", nil
+	}
+
+	primary := make(chan DetailedResult, 1)
+	go func() {
+		primary <- g.DoDetailed("key", fn)
+	}()
+	waitInFlight(t, g, 1)
+
+	var wg sync.WaitGroup
+	waiters := make(chan DetailedResult, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			waiters <- g.DoDetailed("key", fn)
+		}()
+	}
+	waitDups(t, g, "key", 3)
+	close(release)
+	wg.Wait()
+	close(waiters)
+
+	if res := <-primary; res.Role != RolePrimary || res.Shared {
+		t.Errorf("第一个调用者应为执行者, 实际: %v, Shared: %v", res.Role, res.Shared)
+	}
+	for res := range waiters {
+		if res.Role != RoleWaiter || !res.Shared {
+			t.Errorf("并发的调用者应为等待者, 实际: %v, Shared: %v", res.Role, res.Shared)
+		}
+	}
+
+	// 完成后的调用命中缓存
+	if info := g.DoWithInfo("key", fn); info.Role != RoleCacheHit || !info.Shared || info.Val != "ok" {
+		t.Errorf("之后的调用应命中缓存, 实际: %+v", info)
+	}
+
+	// 关闭后的调用被拒绝
+	g.Close()
+	if res := g.DoDetailed("other", fn); res.Role != RoleRejected || res.Err != ErrClosed {
+		t.Errorf("关闭后的调用应被拒绝, 实际: %v, %v", res.Role, res.Err)
+	}
+	if s := RoleCacheHit.String(); s != "cache-hit" {
+		t.Errorf("RoleCacheHit.String() 期望 cache-hit, 实际: %s", s)
+	}
+}
+
+// TestDetailedResultVersion 测试每次重新执行版本号递增, 同一次执行的等待者和缓存命中共享版本号
+func TestDetailedResultVersion(t *testing.T) {
+	g := NewGroupWithTTL(time.Minute)
+	holdUntilJoined(g, map[string]int{"key": 3})
+	fn := func() (interface{}, error) { return "ok", nil }
+
+	var wg sync.WaitGroup
+	results := make(chan DetailedResult, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- g.DoDetailed("key", fn)
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	var first uint64
+	for res := range results {
+		if res.Version == 0 {
+			t.Fatal("执行的结果应有版本号")
+		}
+		if first == 0 {
+			first = res.Version
+		}
+		if res.Version != first {
+			t.Errorf("同一次执行的调用者应共享版本号, 期望 %d, 实际: %d", first, res.Version)
+		}
+	}
+
+	if res := g.DoDetailed("key", fn); res.Role != RoleCacheHit || res.Version != first {
+		t.Errorf("缓存命中应返回产生缓存的执行的版本号 %d, 实际: %v, %d", first, res.Role, res.Version)
+	}
+
+	// 清除缓存后依次重新执行, 版本号递增
+	prev := first
+	for i := 0; i < 3; i++ {
+		g.Forget("key")
+		res := g.DoDetailed("key", fn)
+		if res.Role != RolePrimary || res.Version <= prev {
+			t.Errorf("重新执行的版本号应大于 %d, 实际: %v, %d", prev, res.Role, res.Version)
+		}
+		prev = res.Version
+	}
+}
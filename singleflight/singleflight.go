@@ -1,9 +1,42 @@
 package singleflight
 
 import (
+	"container/list"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// ErrTooManyInFlight 表示进行中的不同 key 数量已达到上限
+var ErrTooManyInFlight = errors.New("singleflight: too many in-flight calls")
+
+// ErrClosed 表示 Group 已经关闭, 不再接受新的调用
+var ErrClosed = errors.New("singleflight: group closed")
+
+// ErrForgotten 表示调用在完成前被 Forget,结果已被丢弃
+// 调用者可以重试以获取最新结果。同步执行 fn 的执行者要等 fn 返回后才收到它, fn 的结果(即使成功)同样被丢弃
+var ErrForgotten = errors.New("singleflight: call forgotten")
+
+// ErrTimeout 表示 fn 执行超过了 WithTimeout 设置的时间
+var ErrTimeout = errors.New("singleflight: call timed out")
+
+// PanicError 表示 fn 执行时发生了 panic
+// 所有等待该调用的调用者都会收到同一个 PanicError,而不是看起来像成功的零值
+type PanicError struct {
+	Value interface{} // recover 得到的原始值
+	Stack []byte      // 发生 panic 时的调用栈
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("singleflight: fn panicked: %v\n\n%s", p.Value, p.Stack)
+}
+
 // Result 是 Do 方法返回的结果
 type Result struct {
 	Val    interface{}
@@ -16,89 +49,741 @@ type call struct {
 	wg  sync.WaitGroup
 	val interface{}
 	err error
+
+	key       string        // 调用方传入的原始 key
+	startedAt time.Time     // fn 开始执行的时间
+	duration  time.Duration // fn 的执行耗时
+	dups      int           // 加入该调用的等待者数量, 只在持有 g.mu 时修改
+	abandoned bool          // 是否在完成前被 Forget 或超时放弃, 只在持有 g.mu 时读写
+	restarted bool          // 同一 key 的上一次调用是否在进行中被 Forget, 即本次是重新开始的执行
+	finished  bool          // 是否已完成或被放弃并唤醒了等待者, 只在持有 g.mu 时读写
+	restarts  int           // 调用者中经历过的最多重新开始次数, 见 WithFairForget, 只在持有 g.mu 时读写
+	version   uint64        // 本次执行的版本号, 见 DetailedResult.Version
+
+	// cancel 非空时取消传给 fn 的 context, 见 DoCancelable
+	// live 是仍在等待的调用者数量, 只在持有 g.mu 时修改, 降为 0 时调用 cancel
+	cancel context.CancelFunc
+	live   int
+
+	ttl time.Duration // 成功结果的缓存时间, 0 表示使用 Group 的缓存时间
+
+	// chans 是通过 DoChan 系列方法等待的调用者(包括执行者自己), fn 完成后统一发送结果,
+	// 避免为每个等待者创建 goroutine。只在持有 g.mu 且 call 仍在 g.m 中时追加
+	chans []waiter
 }
 
 // Group 管理共享相同 key 的请求
 type Group struct {
 	mu sync.Mutex
 	m  map[string]*call
+
+	// RePanic 为 true 时,执行 fn 的 goroutine 在通知所有等待者后重新抛出 panic
+	// 对于 DoChan,fn 在独立的 goroutine 中执行,重新抛出会导致进程退出
+	RePanic bool
+
+	// SlowCallThreshold 和 OnSlowCall 同时设置时, fn 执行超过阈值会触发一次 OnSlowCall,
+	// 只用于告警, 不影响调用结果。OnSlowCall 在独立的 goroutine 中调用
+	SlowCallThreshold time.Duration
+	OnSlowCall        func(key string, elapsed time.Duration)
+
+	// BurstErrorWindow 和 OnBurstError 同时设置时, 相同的错误(按 Error() 文本区分)
+	// 在窗口内只在窗口结束时汇总触发一次 OnBurstError, count 为受影响的调用者数量(包括等待者),
+	// 用于故障期间代替每个调用者各自记录日志。OnBurstError 在独立的 goroutine 中调用
+	BurstErrorWindow time.Duration
+	OnBurstError     func(err error, count int)
+
+	maxInFlight int           // 进行中的不同 key 数量上限, 0 表示不限制
+	timeout     time.Duration // fn 的执行时间上限, 0 表示不限制
+	closed      bool          // 是否已关闭
+
+	// running 是仍在执行的 fn 数量, fn 返回后才减少, 被 Forget 或超时放弃的 fn 也计算在内。
+	// 只在持有 g.mu 且未关闭时增加, 见 Close
+	running sync.WaitGroup
+
+	hashKey func(string) uint64 // 非空时对 key 做哈希后再作为 g.m 的键
+
+	cacheTTL time.Duration          // 已完成结果的缓存时间, 0 表示不缓存
+	cache    map[string]*cacheEntry // 已完成的结果, 键与 g.m 相同
+	cacheMax int                    // 缓存结果的数量上限, 0 表示不限制
+	cacheLRU *list.List             // 缓存结果按最近使用排序, 元素为 g.cache 的键
+
+	forgotten  map[string]struct{} // 进行中被 Forget 的 key, 下一次新建调用时取出并标记为重新开始
+	fairForget int                 // 调用者因 Forget 重新开始的次数上限, 0 表示 Forget 时直接返回 ErrForgotten
+
+	guard ExternalGuard // 执行 fn 前获取的跨进程锁, nil 表示不获取
+
+	version uint64 // 最近一次新建调用分配的版本号, 只在持有 g.mu 时修改
+
+	// 测试钩子, 只在包内测试中设置, 用于不依赖 time.Sleep 地控制执行者与等待者的先后顺序
+	beforeExec func(key string) // 执行者调用 fn 之前调用, 可以阻塞以等待其他调用者加入
+	onJoin     func(key string) // 调用者加入进行中的调用并释放内部锁之后调用
+
+	bursts map[string]*burstError // 窗口内汇总中的错误, 键为错误文本
+
+	streams map[string]*stream // 进行中的 DoStream 调用, 键与 g.m 相同
+
+	canceledWaiters  atomic.Int64 // DoChanContext、DoCancelable 中因 context 结束而放弃等待的调用者数量
+	completedWaiters atomic.Int64 // DoChanContext、DoCancelable 中正常收到结果的调用者数量
+
+	statsEnabled bool         // 是否统计执行与共享次数
+	executions   atomic.Int64 // fn 的执行次数
+	sharedCalls  atomic.Int64 // 加入已有调用、共享结果的次数
+
+	lockStats     bool         // 是否统计等待 g.mu 的时间
+	lockWaitNs    atomic.Int64 // 等待 g.mu 的累计时间(纳秒)
+	lockWaitMaxNs atomic.Int64 // 单次等待 g.mu 的最长时间(纳秒)
+}
+
+// Stats 是 Group 的运行统计
+type Stats struct {
+	// 以下统计只来自 DoChanContext 和 DoCancelable, DoDetached、DoContext 等其他方法不计入
+	CanceledWaiters  int64 // 因 context 结束而放弃等待的调用者数量
+	CompletedWaiters int64 // 正常收到结果的调用者数量
+
+	// 以下统计只在使用 WithStats 创建时记录
+	Executions  int64 // fn 的执行次数
+	SharedCalls int64 // 加入已有调用、共享结果的次数
+
+	// 以下统计只在使用 WithLockStats 创建时记录
+	LockWaitTotal time.Duration // 所有 goroutine 等待内部锁的累计时间
+	LockWaitMax   time.Duration // 单次等待内部锁的最长时间
+}
+
+// Stats 返回当前的运行统计
+func (g *Group) Stats() Stats {
+	return Stats{
+		CanceledWaiters:  g.canceledWaiters.Load(),
+		CompletedWaiters: g.completedWaiters.Load(),
+		Executions:       g.executions.Load(),
+		SharedCalls:      g.sharedCalls.Load(),
+		LockWaitTotal:    time.Duration(g.lockWaitNs.Load()),
+		LockWaitMax:      time.Duration(g.lockWaitMaxNs.Load()),
+	}
+}
+
+// lock 获取 g.mu, 开启锁统计时记录等待时间
+func (g *Group) lock() {
+	if !g.lockStats {
+		g.mu.Lock()
+		return
+	}
+
+	start := time.Now()
+	g.mu.Lock()
+	wait := int64(time.Since(start))
+	g.lockWaitNs.Add(wait)
+	for {
+		max := g.lockWaitMaxNs.Load()
+		if wait <= max || g.lockWaitMaxNs.CompareAndSwap(max, wait) {
+			return
+		}
+	}
+}
+
+// NewGroupWithLimit 创建一个限制进行中 key 数量的 Group
+// 当进行中的不同 key 达到 maxInFlight 时, 新 key 的调用直接返回 ErrTooManyInFlight,
+// 加入已有 key 的等待者不受限制
+func NewGroupWithLimit(maxInFlight int) *Group {
+	return NewGroup(WithMaxInFlight(maxInFlight))
+}
+
+// NewHashedGroup 创建一个对 key 做哈希的 Group
+// key 很长(如完整 URL)时,按 hashFn 得到的定长值存入 g.m,节省内存并加快 map 操作。
+// hashFn 为 nil 时使用 FNVHash。
+// 注意:两个不同的 key 哈希冲突时会被错误地合并为同一个调用,共享同一个结果
+func NewHashedGroup(hashFn func(string) uint64) *Group {
+	return NewGroup(WithKeyHash(hashFn))
+}
+
+// FNVHash 使用 64 位 FNV-1a 计算 key 的哈希值
+func FNVHash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// mapKey 返回 key 在 g.m 中对应的键
+func (g *Group) mapKey(key string) string {
+	if g.hashKey == nil {
+		return key
+	}
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], g.hashKey(key))
+	return string(buf[:])
+}
+
+// full 判断进行中的 key 是否已达到上限, 调用方需持有 g.mu
+func (g *Group) full() bool {
+	return g.maxInFlight > 0 && len(g.m) >= g.maxInFlight
 }
 
 // Do 执行函数 fn,确保对于给定的 key,只调用一次 fn
 // 多个并发调用会等待第一个调用完成,然后共享结果
 func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
-	g.mu.Lock()
+	c, _ := g.do(key, fn)
+	return c.val, c.err
+}
+
+// CallInfo 是 DoWithInfo 返回的结果及调用信息
+type CallInfo struct {
+	Val       interface{}
+	Err       error
+	Shared    bool      // 结果是否是共享的
+	StartedAt time.Time // 共享的 fn 开始执行的时间
+	Role      Role      // 调用者的角色
+}
+
+// DoWithInfo 类似于 Do,但同时返回 fn 开始执行的时间
+// 等待者拿到的是执行者的开始时间,可据此判断结果是否过旧
+func (g *Group) DoWithInfo(key string, fn func() (interface{}, error)) CallInfo {
+	c, role := g.do(key, fn)
+	return CallInfo{Val: c.val, Err: c.err, Shared: role.shared(), StartedAt: c.startedAt, Role: role}
+}
+
+// DetailedResult 是 DoDetailed 返回的结果及完整的调用信息
+type DetailedResult struct {
+	Val       interface{}
+	Err       error
+	Shared    bool          // 结果是否是共享的(调用者是等待者而不是执行者)
+	Dups      int           // 共享该调用的等待者数量
+	Duration  time.Duration // fn 的执行耗时
+	StartedAt time.Time     // fn 开始执行的时间
+	Restarted bool          // 上一次调用在进行中被 Forget, 本次是重新开始的执行
+	Role      Role          // 调用者的角色: 执行者、等待者或缓存命中
+
+	// Version 是产生该结果的那次 fn 执行的版本号, 每次执行 fn 时分配一个更大的值,
+	// 同一次执行的执行者、等待者以及之后命中缓存的调用者拿到相同的版本号。
+	// 版本号在整个 Group 内递增, 对同一个 key, 版本号更大的结果来自更新的执行,
+	// 调用方可以比较手中结果与新结果的版本号判断结果是否已经更新。调用被拒绝时为 0
+	Version uint64
+}
+
+// DoDetailed 类似于 Do,但一次返回结果及所有可观测信息
+// 同一次调用的执行者和等待者拿到相同的 Dups、Duration 和 StartedAt
+func (g *Group) DoDetailed(key string, fn func() (interface{}, error)) DetailedResult {
+	c, role := g.do(key, fn)
+	return DetailedResult{
+		Val:       c.val,
+		Err:       c.err,
+		Shared:    role.shared(),
+		Dups:      c.dups,
+		Duration:  c.duration,
+		StartedAt: c.startedAt,
+		Restarted: c.restarted,
+		Role:      role,
+		Version:   c.version,
+	}
+}
+
+// DoWithDeadline 类似于 Do,但共享的 fn 有一个整体的截止时间
+// fn 收到带该截止时间的 ctx,配合 ctx 提前结束时所有等待者都收到同一个
+// context.DeadlineExceeded 错误,key 随之清除
+func (g *Group) DoWithDeadline(key string, deadline time.Time, fn func(context.Context) (interface{}, error)) (interface{}, error) {
+	c, _ := g.do(key, func() (interface{}, error) {
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		defer cancel()
+
+		val, err := fn(ctx)
+		if err != nil && ctx.Err() != nil {
+			// 统一为截止时间错误, 而不是 fn 自行包装的错误
+			return nil, ctx.Err()
+		}
+		return val, err
+	})
+	return c.val, c.err
+}
+
+// DoWithRetry 类似于 Do,但 fn 失败时按指数退避重试,最多执行 attempts 次
+// 只有执行者重试,并发的调用者共享整个重试过程的最终结果
+// 第 i 次重试前等待 backoff * 2^(i-1)
+func (g *Group) DoWithRetry(key string, attempts int, backoff time.Duration, fn func() (interface{}, error)) (interface{}, error) {
+	c, _ := g.do(key, func() (val interface{}, err error) {
+		delay := backoff
+		for i := 0; i < attempts; i++ {
+			if i > 0 {
+				time.Sleep(delay)
+				delay *= 2
+			}
+			if val, err = fn(); err == nil {
+				return val, nil
+			}
+		}
+		return val, err
+	})
+	return c.val, c.err
+}
+
+// DoCopy 类似于 Do,但每个调用者(包括执行者)拿到的是 copyFn 生成的独立副本
+// 共享结果是 map、slice 等可变类型时,避免调用者之间互相修改
+// fn 返回错误时不调用 copyFn
+func (g *Group) DoCopy(key string, fn func() (interface{}, error), copyFn func(interface{}) interface{}) (interface{}, error) {
+	c, _ := g.do(key, fn)
+	if c.err != nil {
+		return c.val, c.err
+	}
+	return copyFn(c.val), nil
+}
+
+// DoWriteThrough 类似于 Do,但 load 成功后由执行者调用一次 store 把结果写入外部缓存
+// store 在唤醒等待者之前调用, 调用者返回时缓存已写入; 等待者不会重复写缓存。
+// load 返回错误时不调用 store
+func (g *Group) DoWriteThrough(key string, load func() (interface{}, error), store func(key string, val interface{})) (interface{}, error) {
+	c, _ := g.do(key, func() (interface{}, error) {
+		val, err := load()
+		if err == nil {
+			store(key, val)
+		}
+		return val, err
+	})
+	return c.val, c.err
+}
+
+// do 是 Do 系列方法的公共实现, 返回完成的 call 以及调用者的角色
+// 调用被拒绝时返回一个只包含错误的 call
+func (g *Group) do(key string, fn func() (interface{}, error)) (*call, Role) {
+	return g.doTTL(key, 0, fn)
+}
+
+// doTTL 类似于 do, 新建的调用成功后结果缓存 ttl, 0 表示使用 Group 的缓存时间
+// 开启 WithFairForget 时, 被 Forget 的调用者重新加入下一次执行
+func (g *Group) doTTL(key string, ttl time.Duration, fn func() (interface{}, error)) (*call, Role) {
+	for restarts := 0; ; restarts++ {
+		c, role := g.doOnce(key, ttl, fn, restarts)
+		if c.err != ErrForgotten || restarts >= g.fairForget {
+			return c, role
+		}
+	}
+}
+
+// doOnce 执行或加入 key 的一次调用, restarts 是调用者此前因 Forget 重新开始的次数
+func (g *Group) doOnce(key string, ttl time.Duration, fn func() (interface{}, error), restarts int) (*call, Role) {
+	origKey := key
+	key = g.mapKey(key)
+	g.lock()
 	if g.m == nil {
 		g.m = make(map[string]*call)
 	}
 
+	if g.closed {
+		g.mu.Unlock()
+		return &call{err: ErrClosed}, RoleRejected
+	}
+
+	if e, ok := g.lookupCache(key); ok {
+		g.mu.Unlock()
+		return &call{key: origKey, val: e.val, version: e.version}, RoleCacheHit
+	}
+
 	if c, ok := g.m[key]; ok {
+		c.join()
+		c.restarts = max(c.restarts, restarts)
 		g.mu.Unlock()
+		g.countShared()
+		g.notifyJoin(c.key)
 		c.wg.Wait()
-		return c.val, c.err
+		return c, RoleWaiter
 	}
 
-	c := new(call)
+	if g.full() {
+		g.mu.Unlock()
+		return &call{err: ErrTooManyInFlight}, RoleRejected
+	}
+
+	c := &call{key: origKey, startedAt: time.Now(), ttl: ttl, restarted: g.takeForgotten(key), restarts: restarts, version: g.nextVersion()}
 	c.wg.Add(1)
+	g.running.Add(1)
 	g.m[key] = c
 	g.mu.Unlock()
+	g.countExecution()
+	g.run(c, key, fn)
 
-	c.val, c.err = fn()
+	return c, RolePrimary
+}
 
-	c.wg.Done()
+// TryDo 类似于 Do,但 key 已有调用在进行中时不等待
+// 已有调用进行中时立即返回 started=false 且没有结果, 调用方可以改用旧数据;
+// 否则同步执行 fn 并返回其结果, started=true。
+// 命中 TTL 缓存时返回缓存的结果, started=false
+func (g *Group) TryDo(key string, fn func() (interface{}, error)) (val interface{}, err error, started bool) {
+	origKey := key
+	key = g.mapKey(key)
+	g.lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
 
-	g.mu.Lock()
-	delete(g.m, key)
+	if g.closed {
+		g.mu.Unlock()
+		return nil, ErrClosed, false
+	}
+
+	if e, ok := g.lookupCache(key); ok {
+		g.mu.Unlock()
+		return e.val, nil, false
+	}
+
+	if _, ok := g.m[key]; ok {
+		g.mu.Unlock()
+		return nil, nil, false
+	}
+
+	if g.full() {
+		g.mu.Unlock()
+		return nil, ErrTooManyInFlight, false
+	}
+
+	c := &call{key: origKey, startedAt: time.Now(), restarted: g.takeForgotten(key), version: g.nextVersion()}
+	c.wg.Add(1)
+	g.running.Add(1)
+	g.m[key] = c
 	g.mu.Unlock()
+	g.countExecution()
+	g.run(c, key, fn)
 
-	return c.val, c.err
+	return c.val, c.err, true
+}
+
+// run 由执行者调用, 执行 fn 并在完成(或超时被放弃)后返回
+func (g *Group) run(c *call, key string, fn func() (interface{}, error)) {
+	if g.timeout > 0 {
+		// fn 在独立的 goroutine 中执行, 超时后执行者和等待者一样直接返回
+		go g.doCall(c, key, fn)
+		c.wg.Wait()
+		g.checkRePanic(c.err)
+		return
+	}
+	g.checkRePanic(g.doCall(c, key, fn))
+}
+
+// doCall 执行 fn 并通知等待者,fn 发生 panic 时结果为 *PanicError
+// 返回 fn 自身的错误, 供执行者判断是否需要重新抛出 panic
+func (g *Group) doCall(c *call, key string, fn func() (interface{}, error)) (fnErr error) {
+	var val interface{}
+	defer func() {
+		if r := recover(); r != nil {
+			val, fnErr = nil, &PanicError{Value: r, Stack: debug.Stack()}
+		}
+		g.complete(c, key, val, fnErr)
+		g.running.Done()
+	}()
+
+	if g.timeout > 0 {
+		timer := time.AfterFunc(g.timeout, func() {
+			g.abandon(c, key, ErrTimeout)
+		})
+		defer timer.Stop()
+	}
+	if g.SlowCallThreshold > 0 && g.OnSlowCall != nil {
+		// fn 提前结束时停止计时器, 不会留下等待中的 goroutine
+		watchdog := time.AfterFunc(g.SlowCallThreshold, func() {
+			g.OnSlowCall(c.key, time.Since(c.startedAt))
+		})
+		defer watchdog.Stop()
+	}
+	if g.guard != nil {
+		var release func()
+		release, fnErr = g.guard.Acquire(c.key)
+		if fnErr != nil {
+			return fnErr
+		}
+		defer release()
+	}
+	if g.beforeExec != nil {
+		g.beforeExec(c.key)
+	}
+	val, fnErr = fn()
+	return fnErr
+}
+
+// complete 记录 c 的结果并唤醒所有调用者
+func (g *Group) complete(c *call, key string, val interface{}, err error) {
+	g.lock()
+	if c.abandoned {
+		// Forget 或超时已经移除了 c 并唤醒了等待者, 丢弃本次结果,
+		// 也不能再修改等待者可能正在读取的字段
+		g.mu.Unlock()
+		return
+	}
+	c.val, c.err = val, err
+	c.duration = time.Since(c.startedAt)
+	c.finished = true
+	if err != nil {
+		g.recordBurstError(err, 1+c.dups)
+	}
+
+	// 先从 g.m 中移除再唤醒等待者, 之后 dups、chans 等字段不会再被修改
+	// c 已被 Forget 摘除(见 WithFairForget)时 key 可能属于新的调用, 结果也不缓存
+	if g.m[key] == c {
+		delete(g.m, key)
+		g.storeCache(key, c)
+	}
+	g.mu.Unlock()
+
+	c.finish()
+}
+
+// join 记录一个加入 c 的等待者, 调用方需持有 g.mu
+func (c *call) join() {
+	c.dups++
+	c.live++
+}
+
+// nextVersion 分配一个新的版本号, 调用方需持有 g.mu
+func (g *Group) nextVersion() uint64 {
+	g.version++
+	return g.version
+}
+
+// notifyJoin 在设置了测试钩子 onJoin 时报告一个加入 key 的等待者, 调用方不能持有 g.mu
+func (g *Group) notifyJoin(key string) {
+	if g.onJoin != nil {
+		g.onJoin(key)
+	}
+}
+
+// finish 唤醒 c 的所有等待者, 调用方需已将 c 从 g.m 中移除
+func (c *call) finish() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.wg.Done()
+
+	// 不会再有新的等待者追加, 可以安全地广播结果
+	for _, w := range c.chans {
+		w.send(Result{c.val, c.err, !w.owner})
+	}
+}
+
+// checkRePanic 在设置了 RePanic 时重新抛出 fn 的 panic
+func (g *Group) checkRePanic(fnErr error) {
+	var pe *PanicError
+	if g.RePanic && errors.As(fnErr, &pe) {
+		panic(pe)
+	}
 }
 
 // DoChan 类似于 Do,但返回一个 channel
 func (g *Group) DoChan(key string, fn func() (interface{}, error)) <-chan Result {
 	ch := make(chan Result, 1)
-	g.mu.Lock()
+	g.doChan(key, waiter{ch: ch, close: true}, nil, fn)
+	return ch
+}
+
+// DoChanInto 类似于 DoChan,但把结果发送到调用方提供的 ch 而不是新建 channel
+// ch 只会收到一个 Result 且不会被关闭,高吞吐的调用方可以复用 ch 以减少分配。
+// ch 必须带缓冲 (cap(ch) >= 1) 且在收到结果前不被其他调用使用,否则 panic
+func (g *Group) DoChanInto(key string, ch chan Result, fn func() (interface{}, error)) {
+	if cap(ch) < 1 {
+		panic("singleflight: DoChanInto requires a buffered channel")
+	}
+	g.doChan(key, waiter{ch: ch}, nil, fn)
+}
+
+// waiter 是通过 DoChan 系列方法等待结果的 channel
+type waiter struct {
+	ch    chan<- Result
+	close bool // 发送结果后是否关闭 ch
+	owner bool // 是否是执行者自己的 channel, 决定 Result.Shared
+}
+
+// send 发送结果, ch 带有缓冲, 不会阻塞
+func (w waiter) send(res Result) {
+	w.ch <- res
+	if w.close {
+		close(w.ch)
+	}
+}
+
+// doChan 是 DoChan 系列方法的公共实现
+// 新建调用时 cancel 作为取消 fn 的 context 的函数, 见 DoCancelable。
+// 返回加入或新建的 call 以及是否是新建的, 直接得到结果时返回 nil
+func (g *Group) doChan(key string, w waiter, cancel context.CancelFunc, fn func() (interface{}, error)) (*call, bool) {
+	origKey := key
+	key = g.mapKey(key)
+	g.lock()
 	if g.m == nil {
 		g.m = make(map[string]*call)
 	}
 
+	if g.closed {
+		g.mu.Unlock()
+		w.send(Result{nil, ErrClosed, false})
+		return nil, false
+	}
+
+	if e, ok := g.lookupCache(key); ok {
+		g.mu.Unlock()
+		w.send(Result{e.val, nil, true})
+		return nil, false
+	}
+
 	if c, ok := g.m[key]; ok {
+		c.join()
+		c.chans = append(c.chans, w)
+		g.mu.Unlock()
+		g.countShared()
+		g.notifyJoin(c.key)
+		return c, false
+	}
+
+	if g.full() {
 		g.mu.Unlock()
-		go func() {
-			c.wg.Wait()
-			ch <- Result{c.val, c.err, true}
-			close(ch)
-		}()
-		return ch
+		w.send(Result{nil, ErrTooManyInFlight, false})
+		return nil, false
 	}
 
-	c := new(call)
+	// 执行者的 channel 与等待者一起通知, 超时放弃时也能及时收到结果
+	w.owner = true
+	c := &call{key: origKey, startedAt: time.Now(), chans: []waiter{w}, cancel: cancel, live: 1, restarted: g.takeForgotten(key), version: g.nextVersion()}
 	c.wg.Add(1)
+	g.running.Add(1)
 	g.m[key] = c
 	g.mu.Unlock()
+	g.countExecution()
 
 	go func() {
-		c.val, c.err = fn()
-		c.wg.Done()
+		g.checkRePanic(g.doCall(c, key, fn))
+	}()
+	return c, true
+}
 
-		g.mu.Lock()
-		delete(g.m, key)
-		g.mu.Unlock()
+// DoChanContext 类似于 DoChan,但调用者可以通过 ctx 放弃等待
+// ctx 结束时 channel 收到 ctx.Err(),共享的 fn 不受影响,其他调用者仍会收到结果
+func (g *Group) DoChanContext(ctx context.Context, key string, fn func() (interface{}, error)) <-chan Result {
+	ch := make(chan Result, 1)
+	inner := g.DoChan(key, fn)
 
-		ch <- Result{c.val, c.err, false}
-		close(ch)
+	go func() {
+		defer close(ch)
+		select {
+		case res := <-inner:
+			g.completedWaiters.Add(1)
+			ch <- res
+		case <-ctx.Done():
+			g.canceledWaiters.Add(1)
+			ch <- Result{nil, ctx.Err(), false}
+		}
 	}()
 
 	return ch
 }
 
+// DoDetached 类似于 Do,但 fn 在独立的 goroutine 中执行
+// 调用者可以通过 ctx 提前离开,fn 仍会执行完毕并把结果交给其他等待者,
+// 适合即使请求被取消也要完成缓存回填的场景。
+// 不计入 Stats 的 CanceledWaiters、CompletedWaiters
+func (g *Group) DoDetached(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	ch := make(chan Result, 1)
+	g.doChan(key, waiter{ch: ch}, nil, fn)
+
+	select {
+	case res := <-ch:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// DoContext 类似于 DoDetached,但 fn 收到第一个调用者(执行者)的 ctx 中的值,
+// 如 trace ID、认证信息,使共享执行的链路追踪挂在执行者的请求下
+//
+// 注意:
+//   - fn 只能看到执行者 ctx 中的值,等待者各自 ctx 中的值不会传给 fn
+//   - fn 的 context 不继承执行者的取消和截止时间,执行者提前离开不会让等待者收到它的取消错误
+//   - 每个调用者仍可通过自己的 ctx 放弃等待,fn 继续执行并把结果交给其他调用者
+func (g *Group) DoContext(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (interface{}, error) {
+	fnCtx := context.WithoutCancel(ctx)
+	return g.DoDetached(ctx, key, func() (interface{}, error) {
+		return fn(fnCtx)
+	})
+}
+
+// Close 关闭 Group 并等待所有进行中的 fn 执行完毕
+// 关闭后新的调用(包括加入已有 key 的调用)都返回 ErrClosed,
+// 用于平滑下线时避免丢弃正在进行的缓存回填。
+// 已被 Forget 或超时放弃的调用不再有等待者, 但 fn 仍在执行, Close 同样等待它返回;
+// DoStream 的 fn 也计算在内, 但 Close 不等待调用者读完数据流
+func (g *Group) Close() {
+	g.lock()
+	g.closed = true
+	g.mu.Unlock()
+
+	g.running.Wait()
+}
+
 // Forget 用于主动取消某个 key 的等待
-// 使得下一次 Do 调用会重新执行 fn
+// 使得下一次 Do 调用会重新执行 fn, 同时清除该 key 的缓存结果。
+// 正在进行的调用的等待者(包括 DoChan 系列方法的执行者)立即收到 ErrForgotten;
+// 通过 Do 系列方法同步执行 fn 的执行者继续执行, fn 返回后同样收到 ErrForgotten, 其结果(即使成功)被丢弃
 func (g *Group) Forget(key string) {
-	g.mu.Lock()
-	if c, ok := g.m[key]; ok {
+	key = g.mapKey(key)
+	g.lock()
+	g.deleteCache(key)
+	c, ok := g.m[key]
+	if ok && g.fairForget > 0 && c.restarts >= g.fairForget {
+		// 调用者已重新开始到上限: 只摘除 c 让后续调用重新执行, c 的调用者仍等待它的结果
+		delete(g.m, key)
+		g.markForgotten(key)
+		ok = false
+	}
+	g.mu.Unlock()
+
+	if ok {
+		g.abandon(c, key, ErrForgotten)
+	}
+}
+
+// abandon 在 c 完成前放弃它: 从 g.m 中移除并以 err 唤醒所有调用者
+// c 已经完成或已被放弃时什么也不做
+func (g *Group) abandon(c *call, key string, err error) {
+	g.lock()
+	if c.finished {
+		g.mu.Unlock()
+		return
+	}
+	if g.m[key] == c {
 		delete(g.m, key)
-		c.wg.Done()
 	}
+	if err == ErrForgotten {
+		g.markForgotten(key)
+	}
+	c.finished = true
+	c.abandoned = true
+	c.val, c.err = nil, err
+	c.duration = time.Since(c.startedAt)
 	g.mu.Unlock()
+
+	c.finish()
+}
+
+// markForgotten 记录 key 的调用在进行中被 Forget, 调用方需持有 g.mu
+func (g *Group) markForgotten(key string) {
+	if g.forgotten == nil {
+		g.forgotten = make(map[string]struct{})
+	}
+	g.forgotten[key] = struct{}{}
+}
+
+// takeForgotten 返回 key 上一次调用是否在进行中被 Forget, 并清除该标记
+// 调用方需持有 g.mu
+func (g *Group) takeForgotten(key string) bool {
+	_, ok := g.forgotten[key]
+	if ok {
+		delete(g.forgotten, key)
+	}
+	return ok
+}
+
+// countExecution 在开启统计时记录一次 fn 执行
+func (g *Group) countExecution() {
+	if g.statsEnabled {
+		g.executions.Add(1)
+	}
+}
+
+// countShared 在开启统计时记录一次共享结果的调用
+func (g *Group) countShared() {
+	if g.statsEnabled {
+		g.sharedCalls.Add(1)
+	}
 }
@@ -0,0 +1,908 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestMaxInFlight 测试进行中 key 数量上限
+func TestMaxInFlight(t *testing.T) {
+	g := NewGroupWithLimit(2)
+	release := make(chan struct{})
+	var counter int32
+	var wg sync.WaitGroup
+
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&counter, 1)
+		<-release
+		return "result", nil
+	}
+
+	// 占满两个 key, 每个 key 再加一个等待者
+	for _, key := range []string{"a", "b", "a", "b"} {
+		wg.Add(1)
+		go func(k string) {
+			defer wg.Done()
+			if _, err := g.Do(k, fn); err != nil {
+				t.Errorf("key %s 不应该被拒绝: %v", k, err)
+			}
+		}(key)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 第三个不同的 key 应该被拒绝
+	if _, err := g.Do("c", fn); err != ErrTooManyInFlight {
+		t.Errorf("期望 ErrTooManyInFlight, 实际: %v", err)
+	}
+	res := <-g.DoChan("c", fn)
+	if res.Err != ErrTooManyInFlight {
+		t.Errorf("DoChan 期望 ErrTooManyInFlight, 实际: %v", res.Err)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if counter != 2 {
+		t.Errorf("期望执行 2 次, 实际执行 %d 次", counter)
+	}
+
+	// 之前的调用完成后可以接受新的 key
+	if _, err := g.Do("c", func() (interface{}, error) { return "c", nil }); err != nil {
+		t.Errorf("调用完成后应该接受新的 key: %v", err)
+	}
+}
+
+// TestDoChanContextStats 测试取消与完成的等待者统计
+func TestDoChanContextStats(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return "result", nil
+	}
+
+	var chans []<-chan Result
+	var cancels []context.CancelFunc
+	for i := 0; i < 5; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels = append(cancels, cancel)
+		chans = append(chans, g.DoChanContext(ctx, "key", fn))
+	}
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	// 前两个调用者在 fn 执行期间放弃等待
+	cancels[0]()
+	cancels[1]()
+	for i := 0; i < 2; i++ {
+		if res := <-chans[i]; res.Err != context.Canceled {
+			t.Errorf("调用者 %d 期望 context.Canceled, 实际: %v", i, res.Err)
+		}
+	}
+
+	close(release)
+	for i := 2; i < 5; i++ {
+		if res := <-chans[i]; res.Err != nil || res.Val != "result" {
+			t.Errorf("调用者 %d 期望收到结果, 实际: %v, %v", i, res.Val, res.Err)
+		}
+	}
+
+	stats := g.Stats()
+	if stats.CanceledWaiters != 2 || stats.CompletedWaiters != 3 {
+		t.Errorf("期望取消 2 个、完成 3 个, 实际: %+v", stats)
+	}
+}
+
+// TestDoDetached 测试调用者离开后 fn 仍然执行完毕
+func TestDoDetached(t *testing.T) {
+	var g Group
+	var mu sync.Mutex
+	cache := make(map[string]string)
+	done := make(chan struct{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := g.DoDetached(ctx, "key", func() (interface{}, error) {
+		defer close(done)
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		cache["key"] = "value"
+		mu.Unlock()
+		return "value", nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("调用者应该因超时离开, 实际: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("调用者离开后 fn 应该继续执行完毕")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if cache["key"] != "value" {
+		t.Error("fn 执行完毕后应该已写入缓存")
+	}
+
+	if stats := g.Stats(); stats.CanceledWaiters != 0 || stats.CompletedWaiters != 0 {
+		t.Errorf("DoDetached 不应计入 DoChanContext 的等待者统计, 实际: %+v", stats)
+	}
+}
+
+// TestPanicError 测试 fn panic 时所有等待者收到 PanicError
+func TestPanicError(t *testing.T) {
+	var g Group
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+
+	fn := func() (interface{}, error) {
+		<-start
+		panic("boom")
+	}
+
+	errs := make(chan error, 10)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := g.Do("key", fn)
+			errs <- err
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- (<-g.DoChan("key", fn)).Err
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		var pe *PanicError
+		if !errors.As(err, &pe) {
+			t.Fatalf("期望 *PanicError, 实际: %v", err)
+		}
+		if pe.Value != "boom" {
+			t.Errorf("应该保留原始 panic 值, 实际: %v", pe.Value)
+		}
+	}
+}
+
+// TestRePanic 测试设置 RePanic 后执行者重新抛出 panic
+func TestRePanic(t *testing.T) {
+	g := Group{RePanic: true}
+
+	defer func() {
+		r := recover()
+		pe, ok := r.(*PanicError)
+		if !ok || pe.Value != "boom" {
+			t.Errorf("期望重新抛出 *PanicError, 实际: %v", r)
+		}
+	}()
+
+	g.Do("key", func() (interface{}, error) {
+		panic("boom")
+	})
+	t.Error("不应该执行到这里")
+}
+
+// BenchmarkDoChanFanIn 高扇入场景下 DoChan 等待者占用的 goroutine 数量
+func BenchmarkDoChanFanIn(b *testing.B) {
+	const waiters = 1000
+
+	for i := 0; i < b.N; i++ {
+		var g Group
+		release := make(chan struct{})
+		base := runtime.NumGoroutine()
+
+		chans := make([]<-chan Result, waiters)
+		for j := range chans {
+			chans[j] = g.DoChan("key", func() (interface{}, error) {
+				<-release
+				return "result", nil
+			})
+		}
+
+		// 等待者不再各自占用 goroutine, 只有执行 fn 的一个
+		b.ReportMetric(float64(runtime.NumGoroutine()-base), "goroutines/op")
+
+		close(release)
+		for _, ch := range chans {
+			<-ch
+		}
+	}
+}
+
+// TestClose 测试关闭后拒绝新调用并等待进行中的调用完成
+func TestClose(t *testing.T) {
+	var g Group
+	var finished int32
+	started := make(chan struct{})
+
+	go g.Do("key", func() (interface{}, error) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+		return "result", nil
+	})
+	<-started
+
+	g.Close()
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Error("Close 应该等待进行中的调用完成")
+	}
+
+	if _, err := g.Do("key", func() (interface{}, error) { return nil, nil }); err != ErrClosed {
+		t.Errorf("关闭后 Do 期望 ErrClosed, 实际: %v", err)
+	}
+	if res := <-g.DoChan("other", func() (interface{}, error) { return nil, nil }); res.Err != ErrClosed {
+		t.Errorf("关闭后 DoChan 期望 ErrClosed, 实际: %v", res.Err)
+	}
+}
+
+// TestCloseWaitsForgotten 测试 Close 等待已被 Forget、但仍在执行的 fn
+func TestCloseWaitsForgotten(t *testing.T) {
+	var g Group
+	var finished int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	ch := g.DoChan("key", func() (interface{}, error) {
+		close(started)
+		<-release
+		atomic.StoreInt32(&finished, 1)
+		return "result", nil
+	})
+	<-started
+
+	g.Forget("key")
+	if res := <-ch; res.Err != ErrForgotten {
+		t.Fatalf("Forget 后期望 ErrForgotten, 实际: %v", res.Err)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		g.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("fn 仍在执行时 Close 不应该返回")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-closed
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Error("Close 返回时被 Forget 的 fn 应该已经执行完毕")
+	}
+}
+
+// TestDoWithInfo 测试等待者拿到与执行者相同的开始时间
+func TestDoWithInfo(t *testing.T) {
+	var g Group
+	var wg sync.WaitGroup
+	infos := make(chan CallInfo, 5)
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			infos <- g.DoWithInfo("key", func() (interface{}, error) {
+				time.Sleep(50 * time.Millisecond)
+				return "result", nil
+			})
+		}()
+	}
+	wg.Wait()
+	close(infos)
+
+	var startedAt time.Time
+	shared := 0
+	for info := range infos {
+		if info.Val != "result" || info.Err != nil {
+			t.Errorf("结果不匹配: %v, %v", info.Val, info.Err)
+		}
+		if info.StartedAt.IsZero() {
+			t.Fatal("StartedAt 不应为零值")
+		}
+		if startedAt.IsZero() {
+			startedAt = info.StartedAt
+		} else if !info.StartedAt.Equal(startedAt) {
+			t.Errorf("所有调用者应看到相同的开始时间, %v != %v", info.StartedAt, startedAt)
+		}
+		if info.Shared {
+			shared++
+		}
+	}
+	if shared != 4 {
+		t.Errorf("期望 4 个共享结果, 实际: %d", shared)
+	}
+}
+
+// TestDoWithDeadline 测试共享截止时间到达时所有等待者收到相同错误
+func TestDoWithDeadline(t *testing.T) {
+	var g Group
+	var wg sync.WaitGroup
+	var counter int32
+	deadline := time.Now().Add(50 * time.Millisecond)
+
+	slow := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&counter, 1)
+		select {
+		case <-time.After(time.Second):
+			return "result", nil
+		case <-ctx.Done():
+			return nil, fmt.Errorf("query aborted: %w", ctx.Err())
+		}
+	}
+
+	start := time.Now()
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := g.DoWithDeadline("key", deadline, slow)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("应该在截止时间到达后返回, 实际耗时: %v", elapsed)
+	}
+	for err := range errs {
+		if err != context.DeadlineExceeded {
+			t.Errorf("期望 context.DeadlineExceeded, 实际: %v", err)
+		}
+	}
+	if counter != 1 {
+		t.Errorf("期望执行 1 次, 实际执行 %d 次", counter)
+	}
+
+	// key 已清除, 新的调用会重新执行
+	val, err := g.DoWithDeadline("key", time.Now().Add(time.Second), func(ctx context.Context) (interface{}, error) {
+		return "fresh", nil
+	})
+	if val != "fresh" || err != nil {
+		t.Errorf("截止后 key 应该被清除, 实际: %v, %v", val, err)
+	}
+}
+
+// TestHashedGroup 测试对 key 哈希后仍能正常合并请求
+func TestHashedGroup(t *testing.T) {
+	g := NewHashedGroup(nil)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	counts := make(map[string]int)
+
+	longKey := func(i int) string {
+		return fmt.Sprintf("https://example.com/api/v1/search?q=%s&page=%d", strings.Repeat("x", 512), i)
+	}
+
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(k string) {
+			defer wg.Done()
+			val, _ := g.Do(k, func() (interface{}, error) {
+				mu.Lock()
+				counts[k]++
+				mu.Unlock()
+				time.Sleep(50 * time.Millisecond)
+				return k, nil
+			})
+			if val != k {
+				t.Errorf("结果不匹配: %v", val)
+			}
+		}(longKey(i % 3))
+	}
+	wg.Wait()
+
+	if len(counts) != 3 {
+		t.Errorf("期望 3 个不同的 key, 实际: %d", len(counts))
+	}
+	for k, n := range counts {
+		if n != 1 {
+			t.Errorf("key %s... 期望执行 1 次, 实际 %d 次", k[:40], n)
+		}
+	}
+}
+
+// TestDoWithRetry 测试并发调用者共享重试后的最终结果
+func TestDoWithRetry(t *testing.T) {
+	var g Group
+	var wg sync.WaitGroup
+	var counter int32
+
+	fn := func() (interface{}, error) {
+		if atomic.AddInt32(&counter, 1) < 3 {
+			return nil, errors.New("transient error")
+		}
+		return "result", nil
+	}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := g.DoWithRetry("key", 5, 10*time.Millisecond, fn)
+			if val != "result" || err != nil {
+				t.Errorf("期望最终成功, 实际: %v, %v", val, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if counter != 3 {
+		t.Errorf("期望 fn 执行 3 次, 实际执行 %d 次", counter)
+	}
+
+	// 重试次数用尽时返回最后一次的错误
+	_, err := g.DoWithRetry("fail", 2, time.Millisecond, func() (interface{}, error) {
+		return nil, errors.New("permanent error")
+	})
+	if err == nil || err.Error() != "permanent error" {
+		t.Errorf("期望返回最后一次的错误, 实际: %v", err)
+	}
+}
+
+// TestOnSlowCall 测试慢调用告警
+func TestOnSlowCall(t *testing.T) {
+	var slowCalls int32
+	var slowKey atomic.Value
+	g := Group{
+		SlowCallThreshold: 30 * time.Millisecond,
+		OnSlowCall: func(key string, elapsed time.Duration) {
+			atomic.AddInt32(&slowCalls, 1)
+			slowKey.Store(key)
+			if elapsed < 30*time.Millisecond {
+				t.Errorf("告警时的耗时不应小于阈值, 实际: %v", elapsed)
+			}
+		},
+	}
+
+	// 快速调用不触发告警
+	g.Do("fast", func() (interface{}, error) {
+		return "result", nil
+	})
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&slowCalls); n != 0 {
+		t.Errorf("快速调用不应触发告警, 实际触发 %d 次", n)
+	}
+
+	// 慢调用只触发一次告警, 结果不受影响
+	val, err := g.Do("slow", func() (interface{}, error) {
+		time.Sleep(100 * time.Millisecond)
+		return "result", nil
+	})
+	if val != "result" || err != nil {
+		t.Errorf("告警不应影响结果: %v, %v", val, err)
+	}
+	if n := atomic.LoadInt32(&slowCalls); n != 1 {
+		t.Errorf("慢调用应触发 1 次告警, 实际触发 %d 次", n)
+	}
+	if key := slowKey.Load(); key != "slow" {
+		t.Errorf("告警的 key 应为 slow, 实际: %v", key)
+	}
+}
+
+// TestDoCopy 测试每个调用者拿到独立的副本
+func TestDoCopy(t *testing.T) {
+	var g Group
+	var wg sync.WaitGroup
+	var counter int32
+
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&counter, 1)
+		time.Sleep(20 * time.Millisecond)
+		return map[string]int{"count": 0}, nil
+	}
+	copyMap := func(v interface{}) interface{} {
+		src := v.(map[string]int)
+		dst := make(map[string]int, len(src))
+		for k, n := range src {
+			dst[k] = n
+		}
+		return dst
+	}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			val, err := g.DoCopy("key", fn, copyMap)
+			if err != nil {
+				t.Errorf("调用失败: %v", err)
+				return
+			}
+
+			// 各自修改副本, 互不影响
+			m := val.(map[string]int)
+			for j := 0; j < 100; j++ {
+				m["count"]++
+			}
+			m[fmt.Sprintf("caller%d", id)] = id
+			if m["count"] != 100 || len(m) != 2 {
+				t.Errorf("副本被其他调用者修改: %v", m)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if counter != 1 {
+		t.Errorf("期望执行 1 次, 实际执行 %d 次", counter)
+	}
+}
+
+// TestDoDetailed 测试并发调用的详细结果
+func TestDoDetailed(t *testing.T) {
+	var g Group
+	var wg sync.WaitGroup
+	results := make(chan DetailedResult, 5)
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- g.DoDetailed("key", func() (interface{}, error) {
+				time.Sleep(50 * time.Millisecond)
+				return "result", nil
+			})
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	var first *DetailedResult
+	shared := 0
+	for res := range results {
+		res := res
+		if res.Val != "result" || res.Err != nil {
+			t.Errorf("结果不匹配: %v, %v", res.Val, res.Err)
+		}
+		if res.Dups != 4 {
+			t.Errorf("Dups 应为 4, 实际: %d", res.Dups)
+		}
+		if res.Duration < 50*time.Millisecond {
+			t.Errorf("Duration 应不小于 fn 的耗时, 实际: %v", res.Duration)
+		}
+		if first == nil {
+			first = &res
+		} else if !res.StartedAt.Equal(first.StartedAt) || res.Duration != first.Duration {
+			t.Error("同一次调用的 StartedAt 和 Duration 应该相同")
+		}
+		if res.Shared {
+			shared++
+		}
+	}
+	if shared != 4 {
+		t.Errorf("期望 4 个共享结果, 实际: %d", shared)
+	}
+}
+
+// TestDoChanInto 测试结果发送到调用方提供的 channel 且只发送一次
+func TestDoChanInto(t *testing.T) {
+	var g Group
+	var counter int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&counter, 1)
+		<-release
+		return "result", nil
+	}
+
+	chans := make([]chan Result, 5)
+	for i := range chans {
+		chans[i] = make(chan Result, 1)
+		g.DoChanInto("key", chans[i], fn)
+	}
+	close(release)
+
+	for i, ch := range chans {
+		res := <-ch
+		if res.Err != nil || res.Val != "result" {
+			t.Errorf("channel %d 收到错误的结果: %+v", i, res)
+		}
+		select {
+		case extra, ok := <-ch:
+			if ok {
+				t.Errorf("channel %d 收到多余的结果: %+v", i, extra)
+			} else {
+				t.Errorf("channel %d 不应被关闭", i)
+			}
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if counter != 1 {
+		t.Errorf("期望执行 1 次, 实际执行 %d 次", counter)
+	}
+
+	// 复用 channel 发起新的调用
+	g.DoChanInto("key", chans[0], fn)
+	if res := <-chans[0]; res.Val != "result" {
+		t.Errorf("复用 channel 收到错误的结果: %+v", res)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("无缓冲的 channel 应该 panic")
+		}
+	}()
+	g.DoChanInto("key", make(chan Result), fn)
+}
+
+// TestForget 测试 Forget 唤醒的等待者收到 ErrForgotten 而不是看起来成功的 nil 结果
+func TestForget(t *testing.T) {
+	var g Group
+	var counter int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		if atomic.AddInt32(&counter, 1) == 1 {
+			close(started)
+			<-release
+		}
+		return "result", nil
+	}
+
+	primary := make(chan Result, 1)
+	go func() {
+		val, err := g.Do("key", fn)
+		primary <- Result{Val: val, Err: err}
+	}()
+	<-started
+
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := g.Do("key", fn)
+		waiter <- err
+	}()
+	chanWaiter := g.DoChan("key", fn)
+
+	// 等待两个调用者都加入
+	for {
+		g.mu.Lock()
+		dups := g.m["key"].dups
+		g.mu.Unlock()
+		if dups == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	g.Forget("key")
+
+	if err := <-waiter; err != ErrForgotten {
+		t.Errorf("等待者期望收到 ErrForgotten, 实际: %v", err)
+	}
+	if res := <-chanWaiter; res.Err != ErrForgotten || res.Val != nil {
+		t.Errorf("DoChan 等待者期望收到 ErrForgotten, 实际: %+v", res)
+	}
+
+	// Forget 之后的调用重新执行 fn
+	if val, err := g.Do("key", fn); err != nil || val != "result" {
+		t.Errorf("Forget 之后的调用应重新执行, 实际: %v, %v", val, err)
+	}
+
+	// 执行者同步执行 fn, fn 返回前不会返回
+	select {
+	case res := <-primary:
+		t.Fatalf("fn 返回前执行者不应返回, 实际: %+v", res)
+	default:
+	}
+
+	close(release)
+	if res := <-primary; res.Err != ErrForgotten || res.Val != nil {
+		t.Errorf("执行者的 fn 成功返回后结果也应被丢弃并收到 ErrForgotten, 实际: %+v", res)
+	}
+	if counter != 2 {
+		t.Errorf("期望执行 2 次, 实际执行 %d 次", counter)
+	}
+}
+
+// TestTryDo 测试已有调用进行中时 TryDo 立即返回而不等待
+func TestTryDo(t *testing.T) {
+	var g Group
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		val, err, ok := g.TryDo("key", func() (interface{}, error) {
+			close(started)
+			<-release
+			return "slow", nil
+		})
+		if !ok || err != nil || val != "slow" {
+			t.Errorf("第一个调用应执行 fn, 实际: %v, %v, %v", val, err, ok)
+		}
+	}()
+	<-started
+
+	begin := time.Now()
+	val, err, ok := g.TryDo("key", func() (interface{}, error) {
+		t.Error("已有调用进行中, 不应执行 fn")
+		return nil, nil
+	})
+	if ok || val != nil || err != nil {
+		t.Errorf("期望立即返回空结果且 started=false, 实际: %v, %v, %v", val, err, ok)
+	}
+	if elapsed := time.Since(begin); elapsed > 10*time.Millisecond {
+		t.Errorf("TryDo 不应等待进行中的调用, 耗时: %v", elapsed)
+	}
+
+	close(release)
+	<-done
+
+	// 调用完成后再次 TryDo 会执行 fn
+	if val, _, ok := g.TryDo("key", func() (interface{}, error) {
+		return "fresh", nil
+	}); !ok || val != "fresh" {
+		t.Errorf("调用完成后应重新执行, 实际: %v, %v", val, ok)
+	}
+}
+
+// ctxKey 测试用的 context key 类型
+type ctxKey struct{}
+
+// TestDoContext 测试 fn 收到的是第一个调用者 ctx 中的值
+func TestDoContext(t *testing.T) {
+	g := &Group{}
+	release := make(chan struct{})
+	var calls atomic.Int32
+	seen := make(chan interface{}, 2)
+
+	fn := func(ctx context.Context) (interface{}, error) {
+		calls.Add(1)
+		seen <- ctx.Value(ctxKey{})
+		<-release
+		return "ok", nil
+	}
+
+	primaryCtx, cancelPrimary := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "trace-primary"))
+	primaryDone := make(chan error, 1)
+	go func() {
+		_, err := g.DoContext(primaryCtx, "key", fn)
+		primaryDone <- err
+	}()
+
+	if v := <-seen; v != "trace-primary" {
+		t.Errorf("fn 应看到执行者 ctx 中的值, 实际: %v", v)
+	}
+
+	waiterDone := make(chan interface{}, 1)
+	go func() {
+		waiterCtx := context.WithValue(context.Background(), ctxKey{}, "trace-waiter")
+		v, _ := g.DoContext(waiterCtx, "key", fn)
+		waiterDone <- v
+	}()
+	waitDups(t, g, "key", 1)
+
+	// 执行者离开不影响等待者
+	cancelPrimary()
+	if err := <-primaryDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("执行者应收到 context.Canceled, 实际: %v", err)
+	}
+
+	close(release)
+	if v := <-waiterDone; v != "ok" {
+		t.Errorf("等待者应收到共享结果, 实际: %v", v)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fn 应只执行 1 次, 实际: %d", n)
+	}
+}
+
+// TestDoDetailedRestarted 测试进行中被 Forget 后, 下一次执行的结果标记为重新开始
+func TestDoDetailedRestarted(t *testing.T) {
+	var g Group
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	first := make(chan DetailedResult, 1)
+	go func() {
+		first <- g.DoDetailed("key", func() (interface{}, error) {
+			close(started)
+			<-release
+			return "stale", nil
+		})
+	}()
+	<-started
+
+	g.Forget("key")
+	res := g.DoDetailed("key", func() (interface{}, error) { return "fresh", nil })
+	if res.Val != "fresh" || !res.Restarted {
+		t.Errorf("Forget 后的执行应标记为重新开始, 实际: %+v", res)
+	}
+
+	// 执行者在 fn 返回后才收到 ErrForgotten
+	close(release)
+	if res := <-first; res.Err != ErrForgotten || res.Restarted {
+		t.Errorf("被 Forget 的调用应收到 ErrForgotten 且不是重新开始的, 实际: %+v", res)
+	}
+
+	// 正常完成的调用之后不再标记
+	if res := g.DoDetailed("key", func() (interface{}, error) { return "fresh", nil }); res.Restarted {
+		t.Error("正常完成后的执行不应标记为重新开始")
+	}
+
+	// 没有进行中的调用时 Forget 不标记
+	g.Forget("key")
+	if res := g.DoDetailed("key", func() (interface{}, error) { return "fresh", nil }); res.Restarted {
+		t.Error("Forget 时没有进行中的调用, 不应标记为重新开始")
+	}
+}
+
+// TestDoWriteThrough 测试大量并发调用者只写一次缓存
+func TestDoWriteThrough(t *testing.T) {
+	var g Group
+	var loads, stores atomic.Int32
+	var cache sync.Map
+	release := make(chan struct{})
+
+	load := func() (interface{}, error) {
+		loads.Add(1)
+		<-release
+		return "value", nil
+	}
+	store := func(key string, val interface{}) {
+		stores.Add(1)
+		cache.Store(key, val)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := g.DoWriteThrough("key", load, store)
+			if err != nil || val != "value" {
+				t.Errorf("期望 value, 实际: %v, %v", val, err)
+			}
+			if _, ok := cache.Load("key"); !ok {
+				t.Error("调用者返回时缓存应已写入")
+			}
+		}()
+	}
+	// 全部调用者加入同一次调用后再让 load 返回
+	waitDups(t, &g, "key", 49)
+	close(release)
+	wg.Wait()
+
+	if loads.Load() != 1 || stores.Load() != 1 {
+		t.Errorf("应只 load 和 store 各 1 次, 实际 load %d 次, store %d 次", loads.Load(), stores.Load())
+	}
+
+	// load 失败时不写缓存
+	_, err := g.DoWriteThrough("fail", func() (interface{}, error) {
+		return nil, errors.New("boom")
+	}, store)
+	if err == nil {
+		t.Error("load 失败时应返回错误")
+	}
+	if _, ok := cache.Load("fail"); ok {
+		t.Error("load 失败时不应写缓存")
+	}
+}
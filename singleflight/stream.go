@@ -0,0 +1,156 @@
+package singleflight
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+)
+
+// stream 是一次 DoStream 调用, 保存已产生的全部数据供所有订阅者按顺序读取
+type stream struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	items []interface{} // fn 已产生的数据
+	done  bool          // fn 是否已返回
+	err   error         // fn 返回的错误
+}
+
+// StreamResult 是 DoStream 的 channel 收到的一个值
+// Err 为 nil 时 Item 是 fn 通过 emit 产生的数据(数据本身可以是 error 类型);
+// Err 非 nil 时表示数据流以该错误结束, 是 channel 关闭前的最后一个值
+type StreamResult struct {
+	Item interface{}
+	Err  error
+}
+
+// DoStream 类似于 DoChan, 但 fn 可以在执行过程中通过 emit 逐个产生数据,
+// 适合流式返回大查询结果等耗时较长、可以增量输出的计算
+// 相同 key 同时只有一个 fn 在执行, 每个调用者各自拿到一个 channel, 按顺序收到 fn 产生的全部数据,
+// fn 正常返回后 channel 被关闭。fn 返回错误(或 panic, 错误为 *PanicError)时,
+// 关闭前最后收到一个 Err 非 nil 的 StreamResult。
+//
+// 晚加入的调用者同样从第一个数据开始收到完整的数据流: 已产生的数据在 fn 返回前一直保留在内存中,
+// 数据量很大时需要考虑内存占用。emit 不会因为某个调用者读取慢而阻塞。
+//
+// 调用者不再读取时通过 ctx 离开: ctx 结束后负责转发的 goroutine 丢弃尚未读取的数据,
+// 发送 Err 为 ctx.Err() 的结果并关闭 channel, 不会因为无人读取而泄漏; fn 不受影响, 继续为其他调用者执行。
+// Group 已关闭时 channel 只收到 Err 为 ErrClosed 的结果; Close 等待进行中的 fn 返回, 但不等待调用者读完数据
+func (g *Group) DoStream(ctx context.Context, key string, fn func(emit func(interface{})) error) <-chan StreamResult {
+	// 带一个缓冲, 离开时总能放下最后的错误而不阻塞
+	ch := make(chan StreamResult, 1)
+	key = g.mapKey(key)
+
+	g.lock()
+	if g.closed {
+		g.mu.Unlock()
+		ch <- StreamResult{Err: ErrClosed}
+		close(ch)
+		return ch
+	}
+	s, ok := g.streams[key]
+	if !ok {
+		s = &stream{}
+		s.cond = sync.NewCond(&s.mu)
+		if g.streams == nil {
+			g.streams = make(map[string]*stream)
+		}
+		g.streams[key] = s
+		g.running.Add(1)
+	}
+	g.mu.Unlock()
+
+	if ok {
+		g.countShared()
+	} else {
+		g.countExecution()
+		go g.runStream(key, s, fn)
+	}
+	go s.subscribe(ctx, ch)
+	return ch
+}
+
+// runStream 执行 fn 并在返回后结束数据流
+func (g *Group) runStream(key string, s *stream, fn func(emit func(interface{})) error) {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+
+		// 先从 g.streams 中移除, 之后的调用重新执行 fn
+		g.lock()
+		if g.streams[key] == s {
+			delete(g.streams, key)
+		}
+		g.mu.Unlock()
+
+		s.mu.Lock()
+		s.done = true
+		s.err = err
+		s.mu.Unlock()
+		s.cond.Broadcast()
+		g.running.Done()
+	}()
+
+	err = fn(s.emit)
+}
+
+// emit 追加一个数据并唤醒所有订阅者
+func (s *stream) emit(item interface{}) {
+	s.mu.Lock()
+	s.items = append(s.items, item)
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// subscribe 从第一个数据开始依次发送到 ch, 数据流结束或 ctx 结束后关闭 ch
+func (s *stream) subscribe(ctx context.Context, ch chan StreamResult) {
+	defer close(ch)
+
+	// ctx 结束时唤醒等待新数据的订阅者; 先获取 s.mu, 避免在订阅者检查 ctx 之后、Wait 之前广播而丢失唤醒
+	stop := context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		s.mu.Unlock()
+		s.cond.Broadcast()
+	})
+	defer stop()
+
+	for next := 0; ; next++ {
+		s.mu.Lock()
+		for next == len(s.items) && !s.done && ctx.Err() == nil {
+			s.cond.Wait()
+		}
+		if ctx.Err() != nil {
+			s.mu.Unlock()
+			leave(ctx, ch)
+			return
+		}
+		if next == len(s.items) {
+			err := s.err
+			s.mu.Unlock()
+			if err != nil {
+				ch <- StreamResult{Err: err}
+			}
+			return
+		}
+		item := s.items[next]
+		s.mu.Unlock()
+
+		select {
+		case ch <- StreamResult{Item: item}:
+		case <-ctx.Done():
+			leave(ctx, ch)
+			return
+		}
+	}
+}
+
+// leave 在 ctx 结束时丢弃 ch 中尚未读取的数据并放入 ctx.Err()
+// 只有订阅者自己向 ch 发送, 清空缓冲后发送不会阻塞
+func leave(ctx context.Context, ch chan StreamResult) {
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- StreamResult{Err: ctx.Err()}
+}
@@ -0,0 +1,183 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// collect 读完 ch 中的全部数据, 返回数据以及结束时的错误
+func collect(ch <-chan StreamResult) ([]interface{}, error) {
+	var items []interface{}
+	var err error
+	for res := range ch {
+		if res.Err != nil {
+			err = res.Err
+			continue
+		}
+		items = append(items, res.Item)
+	}
+	return items, err
+}
+
+// TestDoStream 测试多个订阅者收到同一个 fn 产生的完整数据流, 包括晚加入的订阅者
+func TestDoStream(t *testing.T) {
+	var g Group
+	ctx := context.Background()
+	var calls atomic.Int32
+	emitted := make(chan struct{})
+	release := make(chan struct{})
+	fn := func(emit func(interface{})) error {
+		calls.Add(1)
+		emit(1)
+		emit(2)
+		close(emitted)
+		<-release
+		emit(3)
+		return nil
+	}
+
+	chans := []<-chan StreamResult{g.DoStream(ctx, "key", fn), g.DoStream(ctx, "key", fn)}
+	// fn 已产生部分数据后加入的订阅者同样从第一个数据开始收到
+	<-emitted
+	chans = append(chans, g.DoStream(ctx, "key", fn))
+	close(release)
+
+	var wg sync.WaitGroup
+	results := make([][]interface{}, len(chans))
+	for i, ch := range chans {
+		wg.Add(1)
+		go func(i int, ch <-chan StreamResult) {
+			defer wg.Done()
+			results[i], _ = collect(ch)
+		}(i, ch)
+	}
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fn 应只执行 1 次, 实际: %d", n)
+	}
+	for i, items := range results {
+		if len(items) != 3 || items[0] != 1 || items[1] != 2 || items[2] != 3 {
+			t.Errorf("订阅者 %d 期望收到 [1 2 3], 实际: %v", i, items)
+		}
+	}
+
+	// 数据流结束后的调用重新执行 fn
+	items, err := collect(g.DoStream(ctx, "key", func(emit func(interface{})) error {
+		emit("again")
+		return nil
+	}))
+	if err != nil || len(items) != 1 || items[0] != "again" {
+		t.Errorf("数据流结束后应重新执行 fn, 实际: %v, %v", items, err)
+	}
+}
+
+// TestDoStreamError 测试 fn 返回错误或 panic 时以 Err 结束, 与 fn 产生的 error 数据区分开
+func TestDoStreamError(t *testing.T) {
+	var g Group
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+	errItem := errors.New("item")
+
+	items, err := collect(g.DoStream(ctx, "key", func(emit func(interface{})) error {
+		emit(errItem)
+		return errFailed
+	}))
+	if len(items) != 1 || items[0] != errItem || err != errFailed {
+		t.Errorf("期望数据 [item] 且以 failed 结束, 实际: %v, %v", items, err)
+	}
+
+	items, err = collect(g.DoStream(ctx, "key", func(emit func(interface{})) error {
+		emit(errItem)
+		return nil
+	}))
+	if len(items) != 1 || items[0] != errItem || err != nil {
+		t.Errorf("fn 产生的 error 数据不应被当作失败, 实际: %v, %v", items, err)
+	}
+
+	_, err = collect(g.DoStream(ctx, "key", func(emit func(interface{})) error {
+		panic("boom")
+	}))
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Errorf("fn panic 时应以 *PanicError 结束, 实际: %v", err)
+	}
+
+	g.Close()
+	items, err = collect(g.DoStream(ctx, "key", func(emit func(interface{})) error { return nil }))
+	if len(items) != 0 || err != ErrClosed {
+		t.Errorf("关闭后应只收到 ErrClosed, 实际: %v, %v", items, err)
+	}
+}
+
+// TestDoStreamLeave 测试停止读取的订阅者取消 ctx 后离开, 其他订阅者不受影响
+func TestDoStreamLeave(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	fn := func(emit func(interface{})) error {
+		for i := 0; i < 10; i++ {
+			emit(i)
+		}
+		<-release
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	left := g.DoStream(ctx, "key", fn)
+	stayed := g.DoStream(context.Background(), "key", fn)
+
+	// 只读一个数据就停止读取, 然后离开
+	if res := <-left; res.Err != nil || res.Item != 0 {
+		t.Fatalf("期望先收到 0, 实际: %+v", res)
+	}
+	cancel()
+
+	var last StreamResult
+	timeout := time.After(time.Second)
+	for done := false; !done; {
+		select {
+		case res, ok := <-left:
+			if !ok {
+				done = true
+				break
+			}
+			last = res
+		case <-timeout:
+			t.Fatal("取消 ctx 后 channel 应该被关闭")
+		}
+	}
+	if last.Err != context.Canceled {
+		t.Errorf("离开时最后应收到 context.Canceled, 实际: %+v", last)
+	}
+
+	close(release)
+	items, err := collect(stayed)
+	if err != nil || len(items) != 10 {
+		t.Errorf("其他订阅者应收到全部 10 个数据, 实际: %v, %v", items, err)
+	}
+}
+
+// TestDoStreamClose 测试 Close 等待进行中的数据流 fn 返回
+func TestDoStreamClose(t *testing.T) {
+	var g Group
+	var finished atomic.Bool
+	started := make(chan struct{})
+
+	ch := g.DoStream(context.Background(), "key", func(emit func(interface{})) error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+		return nil
+	})
+	<-started
+
+	g.Close()
+	if !finished.Load() {
+		t.Error("Close 应该等待数据流的 fn 返回")
+	}
+	collect(ch)
+}
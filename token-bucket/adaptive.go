@@ -0,0 +1,98 @@
+package tokenbucket
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	adaptiveIncrease = 1   // 每次成功时速率的加性增量
+	adaptiveDecrease = 0.5 // 每次失败时速率的乘性减少因子
+)
+
+// AdaptiveTokenBucket 根据下游反馈自动调整速率的令牌桶
+// 采用类似 TCP 拥塞控制的加性增、乘性减（AIMD）策略：
+// 每次成功速率加 1，每次失败速率减半，速率始终在 [minRate, maxRate] 之间
+type AdaptiveTokenBucket struct {
+	tb      *TokenBucket
+	mu      sync.Mutex
+	rate    int // 当前速率
+	minRate int // 最低速率
+	maxRate int // 最高速率
+}
+
+// NewAdaptiveTokenBucket 创建一个自适应速率的令牌桶，初始速率为 maxRate
+// capacity: 桶的容量
+// minRate: 最低速率（每秒）
+// maxRate: 最高速率（每秒）
+func NewAdaptiveTokenBucket(capacity, minRate, maxRate int) *AdaptiveTokenBucket {
+	return NewAdaptiveTokenBucketWithClock(capacity, minRate, maxRate, realClock{})
+}
+
+// NewAdaptiveTokenBucketWithClock 使用指定的时间来源创建自适应速率的令牌桶
+func NewAdaptiveTokenBucketWithClock(capacity, minRate, maxRate int, clock Clock) *AdaptiveTokenBucket {
+	return &AdaptiveTokenBucket{
+		tb:      NewTokenBucketWithClock(capacity, maxRate, clock),
+		rate:    maxRate,
+		minRate: minRate,
+		maxRate: maxRate,
+	}
+}
+
+// TryConsume 按当前自适应速率尝试消费令牌
+func (atb *AdaptiveTokenBucket) TryConsume(count int) bool {
+	return atb.tb.TryConsume(count)
+}
+
+// ReportSuccess 报告一次下游调用成功，速率加性增加
+func (atb *AdaptiveTokenBucket) ReportSuccess() {
+	atb.mu.Lock()
+	defer atb.mu.Unlock()
+
+	atb.updateRate(atb.rate + adaptiveIncrease)
+}
+
+// ReportFailure 报告一次下游调用失败，速率乘性减少
+func (atb *AdaptiveTokenBucket) ReportFailure() {
+	atb.mu.Lock()
+	defer atb.mu.Unlock()
+
+	atb.updateRate(int(float64(atb.rate) * adaptiveDecrease))
+}
+
+// ObserveResponse 根据下游 HTTP 响应调整速率，用于客户端按服务端的限额自我调节
+// 429 Too Many Requests 时速率乘性减少，retryAfter 大于 0（来自 Retry-After 头）时
+// 清空令牌桶，retryAfter 时间内的 TryConsume 全部失败；2xx 时速率加性增加；其他状态码不影响速率
+func (atb *AdaptiveTokenBucket) ObserveResponse(statusCode int, retryAfter time.Duration) {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		atb.ReportFailure()
+		if retryAfter > 0 {
+			atb.tb.drainUntil(atb.tb.now().Add(retryAfter))
+		}
+	case statusCode >= 200 && statusCode < 300:
+		atb.ReportSuccess()
+	}
+}
+
+// updateRate 将速率限制在 [minRate, maxRate] 内并同步到令牌桶，调用方需持有 atb.mu
+func (atb *AdaptiveTokenBucket) updateRate(rate int) {
+	if rate < atb.minRate {
+		rate = atb.minRate
+	}
+	if rate > atb.maxRate {
+		rate = atb.maxRate
+	}
+	if rate != atb.rate {
+		atb.rate = rate
+		atb.tb.setRate(rate)
+	}
+}
+
+// Rate 返回当前速率
+func (atb *AdaptiveTokenBucket) Rate() int {
+	atb.mu.Lock()
+	defer atb.mu.Unlock()
+	return atb.rate
+}
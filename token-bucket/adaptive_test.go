@@ -0,0 +1,77 @@
+package tokenbucket
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestAdaptiveRate 测试失败时速率下降、成功后逐步恢复
+func TestAdaptiveRate(t *testing.T) {
+	atb := NewAdaptiveTokenBucket(10, 10, 100)
+
+	// 连续失败，速率减半直到最低速率
+	for i := 0; i < 10; i++ {
+		atb.ReportFailure()
+	}
+	if got := atb.Rate(); got != 10 {
+		t.Errorf("连续失败后速率应降到最低 10, 实际: %d", got)
+	}
+	if atb.tb.rate != 10 {
+		t.Errorf("令牌桶应使用调整后的速率 10, 实际: %d", atb.tb.rate)
+	}
+
+	// 成功后加性恢复
+	for i := 0; i < 20; i++ {
+		atb.ReportSuccess()
+	}
+	if got := atb.Rate(); got != 30 {
+		t.Errorf("20 次成功后速率应为 30, 实际: %d", got)
+	}
+
+	// 不超过最高速率
+	for i := 0; i < 200; i++ {
+		atb.ReportSuccess()
+	}
+	if got := atb.Rate(); got != 100 {
+		t.Errorf("速率不应超过最高 100, 实际: %d", got)
+	}
+
+	if !atb.TryConsume(10) {
+		t.Error("桶满时消费应该成功")
+	}
+}
+
+// TestObserveResponse 测试 429 带 Retry-After 时在指定时间内拒绝所有请求
+func TestObserveResponse(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	atb := NewAdaptiveTokenBucketWithClock(10, 10, 100, clock)
+
+	atb.ObserveResponse(http.StatusTooManyRequests, 2*time.Second)
+	if got := atb.Rate(); got != 50 {
+		t.Errorf("429 后速率应减半为 50, 实际: %d", got)
+	}
+	if atb.TryConsume(1) {
+		t.Error("429 后令牌桶应被清空")
+	}
+
+	clock.Advance(1999 * time.Millisecond)
+	if atb.TryConsume(1) {
+		t.Error("Retry-After 时间内不应放行")
+	}
+
+	// Retry-After 结束后按新速率 50/s 补充, 20ms 一个令牌
+	clock.Advance(21 * time.Millisecond)
+	if !atb.TryConsume(1) {
+		t.Error("Retry-After 结束后应按新速率恢复")
+	}
+
+	atb.ObserveResponse(http.StatusOK, 0)
+	if got := atb.Rate(); got != 51 {
+		t.Errorf("2xx 后速率应加 1, 实际: %d", got)
+	}
+	atb.ObserveResponse(http.StatusInternalServerError, 0)
+	if got := atb.Rate(); got != 51 {
+		t.Errorf("其他状态码不应影响速率, 实际: %d", got)
+	}
+}
@@ -0,0 +1,86 @@
+package tokenbucket
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 时间来源，便于在测试中使用可控的时间
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock 使用系统时间
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// timerClock 是可以创建定时器的时间来源
+// Wait 使用时间来源的定时器等待令牌，时间来源未实现该接口时使用系统定时器
+type timerClock interface {
+	After(d time.Duration) <-chan time.Time
+}
+
+// ManualClock 手动推进的时钟，用于确定性地测试令牌补充
+type ManualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []manualWaiter // After 创建的尚未到期的定时器
+}
+
+// manualWaiter 是 ManualClock 的一个定时器
+type manualWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewManualClock 创建一个从 start 开始的手动时钟
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now 返回当前时间
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance 将时钟向前推进 d
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+
+	// 触发到期的定时器
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.deadline.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+// After 返回一个在时钟推进 d 之后收到当前时间的 channel
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, manualWaiter{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// pendingTimers 返回尚未到期的定时器数量，测试用于等待调用方开始等待
+func (c *ManualClock) pendingTimers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
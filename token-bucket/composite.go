@@ -0,0 +1,126 @@
+package tokenbucket
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // 关闭：正常放行
+	BreakerOpen                         // 打开：拒绝所有请求
+	BreakerHalfOpen                     // 半开：放行一个探测请求
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CompositeLimiter 组合令牌桶与熔断器的限流器
+// 熔断器打开时无论令牌是否充足都拒绝请求，避免向不健康的下游发送无用请求
+type CompositeLimiter struct {
+	tb               *TokenBucket
+	mu               sync.Mutex
+	state            BreakerState
+	failures         int           // 连续失败次数
+	failureThreshold int           // 触发熔断的连续失败次数
+	openTimeout      time.Duration // 熔断打开后进入半开状态的等待时间
+	openedAt         time.Time     // 熔断打开的时间
+	probing          bool          // 半开状态下是否已放行探测请求
+	clock            Clock         // 熔断器计时使用的时间来源
+}
+
+// NewCompositeLimiter 创建一个组合限流器
+// failureThreshold: 连续失败多少次后打开熔断器
+// openTimeout: 熔断器打开多久后进入半开状态
+func NewCompositeLimiter(tb *TokenBucket, failureThreshold int, openTimeout time.Duration) *CompositeLimiter {
+	return NewCompositeLimiterWithClock(tb, failureThreshold, openTimeout, realClock{})
+}
+
+// NewCompositeLimiterWithClock 使用指定的时间来源创建组合限流器
+// clock 只用于熔断器计时，令牌桶使用自己的时间来源，测试时通常传入同一个 ManualClock
+func NewCompositeLimiterWithClock(tb *TokenBucket, failureThreshold int, openTimeout time.Duration, clock Clock) *CompositeLimiter {
+	return &CompositeLimiter{
+		tb:               tb,
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		clock:            clock,
+	}
+}
+
+// Allow 判断请求是否放行：熔断器允许且令牌充足时消费 1 个令牌并返回 true
+// 半开状态下只放行一个探测请求，结果由 RecordSuccess/RecordFailure 决定熔断器状态
+func (cl *CompositeLimiter) Allow() bool {
+	ok, _ := cl.AllowWithReason(1)
+	return ok
+}
+
+// AllowWithReason 类似于 Allow，但消费 n 个令牌并返回判定原因
+// 熔断器拒绝时为 ReasonBreakerOpen，令牌不足时为 ReasonInsufficientTokens
+func (cl *CompositeLimiter) AllowWithReason(n int) (bool, Reason) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.state == BreakerOpen && cl.clock.Now().Sub(cl.openedAt) >= cl.openTimeout {
+		cl.state = BreakerHalfOpen
+		cl.probing = false
+	}
+
+	switch cl.state {
+	case BreakerOpen:
+		return false, ReasonBreakerOpen
+	case BreakerHalfOpen:
+		if cl.probing {
+			return false, ReasonBreakerOpen
+		}
+		if ok, reason := cl.tb.AllowWithReason(n); !ok {
+			return false, reason
+		}
+		cl.probing = true
+		return true, ReasonOK
+	default:
+		return cl.tb.AllowWithReason(n)
+	}
+}
+
+// RecordSuccess 记录一次下游调用成功，半开状态下关闭熔断器
+func (cl *CompositeLimiter) RecordSuccess() {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	cl.failures = 0
+	if cl.state == BreakerHalfOpen {
+		cl.state = BreakerClosed
+	}
+}
+
+// RecordFailure 记录一次下游调用失败
+// 连续失败达到阈值或半开状态下探测失败时打开熔断器
+func (cl *CompositeLimiter) RecordFailure() {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	cl.failures++
+	if cl.state == BreakerHalfOpen || cl.failures >= cl.failureThreshold {
+		cl.state = BreakerOpen
+		cl.openedAt = cl.clock.Now()
+	}
+}
+
+// State 返回熔断器当前状态
+func (cl *CompositeLimiter) State() BreakerState {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.state
+}
@@ -0,0 +1,86 @@
+package tokenbucket
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCompositeLimiterBreaker 测试熔断器打开时即使令牌充足也拒绝请求
+func TestCompositeLimiterBreaker(t *testing.T) {
+	cl := NewCompositeLimiter(NewTokenBucket(100, 100), 3, 50*time.Millisecond)
+
+	if !cl.Allow() {
+		t.Fatal("熔断器关闭且令牌充足时应该放行")
+	}
+
+	// 连续失败触发熔断
+	for i := 0; i < 3; i++ {
+		cl.RecordFailure()
+	}
+	if cl.State() != BreakerOpen {
+		t.Fatalf("连续失败后熔断器应该打开, 实际: %v", cl.State())
+	}
+	if cl.Allow() {
+		t.Error("熔断器打开时即使令牌充足也应该拒绝")
+	}
+
+	// 等待进入半开状态，只放行一个探测请求
+	time.Sleep(60 * time.Millisecond)
+	if !cl.Allow() {
+		t.Fatal("半开状态应该放行探测请求")
+	}
+	if cl.Allow() {
+		t.Error("半开状态只应放行一个探测请求")
+	}
+
+	// 探测失败重新打开
+	cl.RecordFailure()
+	if cl.State() != BreakerOpen {
+		t.Fatalf("探测失败后熔断器应该重新打开, 实际: %v", cl.State())
+	}
+
+	// 再次探测成功后关闭
+	time.Sleep(60 * time.Millisecond)
+	if !cl.Allow() {
+		t.Fatal("半开状态应该放行探测请求")
+	}
+	cl.RecordSuccess()
+	if cl.State() != BreakerClosed {
+		t.Errorf("探测成功后熔断器应该关闭, 实际: %v", cl.State())
+	}
+	if !cl.Allow() {
+		t.Error("熔断器关闭后应该放行")
+	}
+}
+
+// TestCompositeLimiterClock 测试熔断器按注入的时间来源进入半开状态
+func TestCompositeLimiterClock(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cl := NewCompositeLimiterWithClock(NewTokenBucketWithClock(100, 100, clock), 1, time.Minute, clock)
+
+	cl.RecordFailure()
+	clock.Advance(time.Minute - time.Nanosecond)
+	if cl.Allow() {
+		t.Fatal("打开时长未到 openTimeout 时应该拒绝")
+	}
+
+	clock.Advance(time.Nanosecond)
+	if !cl.Allow() {
+		t.Fatal("时钟推进到 openTimeout 后应该进入半开状态并放行探测请求")
+	}
+	if cl.State() != BreakerHalfOpen {
+		t.Errorf("熔断器应该处于半开状态, 实际: %v", cl.State())
+	}
+}
+
+// TestCompositeLimiterTokens 测试熔断器关闭时仍受令牌限制
+func TestCompositeLimiterTokens(t *testing.T) {
+	cl := NewCompositeLimiter(NewTokenBucket(2, 1), 3, time.Second)
+
+	if !cl.Allow() || !cl.Allow() {
+		t.Fatal("令牌充足时应该放行")
+	}
+	if cl.Allow() {
+		t.Error("令牌耗尽时应该拒绝")
+	}
+}
@@ -0,0 +1,56 @@
+package tokenbucket
+
+import (
+	"sort"
+	"unsafe"
+)
+
+// ConsumeAll 原子地从多个令牌桶消费令牌
+// 从 buckets[i] 消费 counts[i] 个令牌，全部成功或全部不消费，
+// 用于一次操作同时受多个限流器约束的场景（如带宽和请求数）。
+// 按指针地址顺序加锁，并发调用不会死锁；同一个令牌桶出现多次时合并计算。
+// buckets 与 counts 长度不一致时返回 false
+func ConsumeAll(buckets []*TokenBucket, counts []int) bool {
+	if len(buckets) != len(counts) {
+		return false
+	}
+
+	// 合并重复的令牌桶，避免对同一把锁加锁两次
+	need := make(map[*TokenBucket]int, len(buckets))
+	for i, tb := range buckets {
+		need[tb] += counts[i]
+	}
+	ordered := make([]*TokenBucket, 0, len(need))
+	for tb := range need {
+		ordered = append(ordered, tb)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return uintptr(unsafe.Pointer(ordered[i])) < uintptr(unsafe.Pointer(ordered[j]))
+	})
+
+	for _, tb := range ordered {
+		tb.lock()
+		defer tb.unlock()
+	}
+
+	// 持有所有锁时先检查再消费，不会出现需要回滚的部分消费
+	ok := true
+	for _, tb := range ordered {
+		tb.refill()
+		if tb.tokens < need[tb] {
+			tb.rejected++
+			ok = false
+		}
+	}
+	if !ok {
+		return false
+	}
+
+	for _, tb := range ordered {
+		tb.tokens -= need[tb]
+		tb.consumed += int64(need[tb])
+		tb.allowed++
+		tb.notifyConsume(need[tb])
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package tokenbucket
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConsumeAll 测试任一令牌桶不足时不扣减其他令牌桶
+func TestConsumeAll(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	bandwidth := NewTokenBucketWithClock(100, 10, clock)
+	requests := NewTokenBucketWithClock(1, 1, clock)
+
+	buckets := []*TokenBucket{bandwidth, requests}
+	if !ConsumeAll(buckets, []int{30, 1}) {
+		t.Fatal("两个令牌桶都足够时应该成功")
+	}
+	if ConsumeAll(buckets, []int{30, 1}) {
+		t.Fatal("请求数令牌桶不足时应该失败")
+	}
+	if got := bandwidth.GetTokens(); got != 70 {
+		t.Errorf("失败时带宽令牌桶不应被扣减, 期望 70, 实际: %d", got)
+	}
+
+	// 重复出现的令牌桶合并计算
+	if ConsumeAll([]*TokenBucket{bandwidth, bandwidth}, []int{40, 40}) {
+		t.Error("合计 80 个令牌超过剩余的 70 个, 应该失败")
+	}
+	if ConsumeAll(buckets, []int{1}) {
+		t.Error("长度不一致时应该失败")
+	}
+}
+
+// TestConsumeAllNoDeadlock 测试以相反顺序并发调用不会死锁
+func TestConsumeAllNoDeadlock(t *testing.T) {
+	a := NewTokenBucket(1000000, 0)
+	b := NewTokenBucket(1000000, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			ConsumeAll([]*TokenBucket{a, b}, []int{1, 1})
+		}()
+		go func() {
+			defer wg.Done()
+			ConsumeAll([]*TokenBucket{b, a}, []int{1, 1})
+		}()
+	}
+	wg.Wait()
+
+	if a.GetTokens() != 1000000-200 || b.GetTokens() != 1000000-200 {
+		t.Errorf("每个令牌桶应被扣减 200 个令牌, 实际剩余: %d, %d", a.GetTokens(), b.GetTokens())
+	}
+}
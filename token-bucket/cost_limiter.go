@@ -0,0 +1,37 @@
+package tokenbucket
+
+// CostLimiter 按操作类型消费不同数量令牌的限流器
+// 集中配置各操作的权重，例如批量接口比普通查询消耗更多令牌
+type CostLimiter struct {
+	tb          *TokenBucket
+	costs       map[string]int // 操作名到令牌消耗的映射
+	defaultCost int            // 未配置操作的令牌消耗
+}
+
+// NewCostLimiter 创建一个按操作权重限流的限流器
+// costs: 各操作消耗的令牌数
+// defaultCost: 未在 costs 中配置的操作消耗的令牌数
+func NewCostLimiter(tb *TokenBucket, costs map[string]int, defaultCost int) *CostLimiter {
+	copied := make(map[string]int, len(costs))
+	for op, cost := range costs {
+		copied[op] = cost
+	}
+	return &CostLimiter{
+		tb:          tb,
+		costs:       copied,
+		defaultCost: defaultCost,
+	}
+}
+
+// Cost 返回操作消耗的令牌数
+func (cl *CostLimiter) Cost(op string) int {
+	if cost, ok := cl.costs[op]; ok {
+		return cost
+	}
+	return cl.defaultCost
+}
+
+// Allow 按操作的权重尝试消费令牌
+func (cl *CostLimiter) Allow(op string) bool {
+	return cl.tb.TryConsume(cl.Cost(op))
+}
@@ -0,0 +1,40 @@
+package tokenbucket
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCostLimiter 测试不同操作按权重消费令牌
+func TestCostLimiter(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cl := NewCostLimiter(NewTokenBucketWithClock(10, 1, clock), map[string]int{
+		"get":  1,
+		"list": 3,
+		"bulk": 8,
+	}, 2)
+
+	if cl.Cost("unknown") != 2 {
+		t.Errorf("未配置的操作应使用默认消耗 2, 实际: %d", cl.Cost("unknown"))
+	}
+
+	steps := []struct {
+		op   string
+		want bool
+	}{
+		{"bulk", true},    // 剩余 2
+		{"list", false},   // 需要 3
+		{"unknown", true}, // 剩余 0
+		{"get", false},
+	}
+	for _, s := range steps {
+		if got := cl.Allow(s.op); got != s.want {
+			t.Errorf("%s: 期望 %v, 实际 %v", s.op, s.want, got)
+		}
+	}
+
+	clock.Advance(3 * time.Second)
+	if !cl.Allow("list") {
+		t.Error("补充 3 个令牌后 list 应该通过")
+	}
+}
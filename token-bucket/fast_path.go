@@ -0,0 +1,73 @@
+package tokenbucket
+
+import "math"
+
+// fastBias 是快速路径令牌数的偏移量
+// 令牌数可能因透支或预留为负，加上偏移后仍为正数，从而可以用 0 表示快速路径关闭
+const fastBias = 1 << 62
+
+// 无锁快速路径
+//
+// 多数 TryConsume 发生在两次令牌生成之间，此时 refill 不会补充任何令牌，
+// 只需检查并扣减令牌数。快速路径把令牌数放在 fast 中，通过 CAS 扣减，不获取锁：
+//   - 加锁的 TryConsume 结束前调用 arm，把当前令牌数和下一个令牌生成的时刻发布到 fast、fastNext
+//   - 其他方法通过 lock 获取锁，lock 调用 settle 关闭快速路径，并把 fast 中的令牌数和计数并回普通字段，
+//     之后可以像以前一样直接读写 tokens
+//   - 到达 fastNext（需要补充令牌）、令牌不足、CAS 冲突或快速路径已关闭时，回退到加锁路径
+//
+// 设置了 OnConsume 时回调需要在锁内调用，不开启快速路径
+
+// tryConsumeFast 尝试不加锁消费 count 个令牌，返回 false 时调用方需走加锁路径
+func (tb *TokenBucket) tryConsumeFast(count int) bool {
+	v := tb.fast.Load()
+	if v-fastBias < int64(count) {
+		return false
+	}
+	if next := tb.fastNext.Load(); next != math.MaxInt64 && tb.now().UnixNano() >= next {
+		return false
+	}
+	if !tb.fast.CompareAndSwap(v, v-int64(count)) {
+		return false
+	}
+	tb.fastAllow.Add(1)
+	return true
+}
+
+// lock 获取锁并关闭快速路径，之后可以直接读写 tokens 等字段
+// 快速路径只会减少令牌数，并回后的令牌数即快速路径期间的最小值，随之记入 Extremes
+func (tb *TokenBucket) lock() {
+	tb.mu.Lock()
+	tb.settle()
+	tb.observe()
+}
+
+// unlock 记录临界区结束时的令牌数并释放锁
+func (tb *TokenBucket) unlock() {
+	tb.observe()
+	tb.mu.Unlock()
+}
+
+// settle 关闭快速路径，并把快速路径的令牌数和计数并回普通字段，调用方需持有锁
+// 快速路径消费的令牌数即发布时的令牌数与当前令牌数之差
+func (tb *TokenBucket) settle() {
+	if v := tb.fast.Swap(0); v != 0 {
+		tokens := int(v - fastBias)
+		tb.consumed += int64(tb.tokens - tokens)
+		tb.tokens = tokens
+	}
+	tb.allowed += tb.fastAllow.Swap(0)
+}
+
+// arm 发布当前令牌数，开启快速路径，调用方需持有锁
+// 先发布 fastNext 再发布令牌数，读到新令牌数的快速路径一定能读到对应的 fastNext
+func (tb *TokenBucket) arm() {
+	if tb.OnConsume != nil {
+		return
+	}
+	next := int64(math.MaxInt64)
+	if per := tb.tokenInterval(); per > 0 {
+		next = tb.lastRefill.Add(per).UnixNano()
+	}
+	tb.fastNext.Store(next)
+	tb.fast.Store(int64(tb.tokens) + fastBias)
+}
@@ -0,0 +1,101 @@
+package tokenbucket
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestFastPathContention 测试并发消费时快速路径与加锁路径交替执行，令牌不会多发也不会丢失
+func TestFastPathContention(t *testing.T) {
+	const capacity = 10000
+	tb := NewTokenBucket(capacity, 0)
+
+	var ok atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 2000; j++ {
+				if tb.TryConsume(1) {
+					ok.Add(1)
+				}
+			}
+		}()
+	}
+	// 加锁的读取会关闭快速路径，与快速路径的 CAS 交错执行
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			tb.GetTokens()
+			tb.Counts()
+		}
+	}()
+	wg.Wait()
+
+	if ok.Load() != capacity {
+		t.Errorf("成功消费次数期望 %d, 实际: %d", capacity, ok.Load())
+	}
+	allowed, rejected := tb.Counts()
+	if allowed != capacity || rejected != 8*2000-capacity {
+		t.Errorf("计数期望 %d/%d, 实际: %d/%d", capacity, 8*2000-capacity, allowed, rejected)
+	}
+	if tokens := tb.GetTokens(); tokens != 0 {
+		t.Errorf("令牌应全部消费, 实际剩余: %d", tokens)
+	}
+}
+
+// TestFastPathRefill 测试到达下一个令牌生成时刻后回退到加锁路径补充令牌
+func TestFastPathRefill(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	tb := NewTokenBucketWithClock(2, 10, clock)
+
+	if !tb.TryConsume(1) || !tb.TryConsume(1) {
+		t.Fatal("桶满时消费应成功")
+	}
+	if tb.TryConsume(1) {
+		t.Error("令牌耗尽后消费应失败")
+	}
+
+	clock.Advance(100 * time.Millisecond)
+	if !tb.TryConsume(1) {
+		t.Error("补充一个令牌后消费应成功")
+	}
+	if tb.TryConsume(1) {
+		t.Error("只补充了一个令牌, 第二次消费应失败")
+	}
+}
+
+// BenchmarkTryConsumeUncontended 单线程消费，两次令牌生成之间走无锁快速路径
+func BenchmarkTryConsumeUncontended(b *testing.B) {
+	tb := NewTokenBucket(math.MaxInt32, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tb.TryConsume(1)
+	}
+}
+
+// BenchmarkTryConsumeLocked 单线程消费，设置 OnConsume 强制走加锁路径，作为对照
+func BenchmarkTryConsumeLocked(b *testing.B) {
+	tb := NewTokenBucket(math.MaxInt32, 1)
+	tb.OnConsume = func(n, remaining int) {}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tb.TryConsume(1)
+	}
+}
+
+// BenchmarkTryConsumeParallel 并发消费
+func BenchmarkTryConsumeParallel(b *testing.B) {
+	tb := NewTokenBucket(math.MaxInt32, 1)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			tb.TryConsume(1)
+		}
+	})
+}
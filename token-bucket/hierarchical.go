@@ -0,0 +1,32 @@
+package tokenbucket
+
+// HierarchicalLimiter 全局 + 租户两级限流器
+// 请求需要同时通过租户自己的令牌桶和全局令牌桶，
+// 既防止单个租户占满全局配额，又保证总量不超过全局上限
+type HierarchicalLimiter struct {
+	global  *TokenBucket  // 全局令牌桶
+	tenants *KeyedLimiter // 每个租户的令牌桶
+}
+
+// NewHierarchicalLimiter 创建一个两级限流器
+func NewHierarchicalLimiter(global *TokenBucket, tenants *KeyedLimiter) *HierarchicalLimiter {
+	return &HierarchicalLimiter{
+		global:  global,
+		tenants: tenants,
+	}
+}
+
+// Allow 判断租户的请求是否放行
+// 先消费租户令牌，再消费全局令牌；全局令牌不足时归还租户令牌，
+// 避免被全局限流的请求占用租户配额
+func (hl *HierarchicalLimiter) Allow(tenant string) bool {
+	tb := hl.tenants.Bucket(tenant)
+	if !tb.TryConsume(1) {
+		return false
+	}
+	if !hl.global.TryConsume(1) {
+		tb.Refund(1)
+		return false
+	}
+	return true
+}
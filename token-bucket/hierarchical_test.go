@@ -0,0 +1,38 @@
+package tokenbucket
+
+import (
+	"testing"
+	"time"
+)
+
+// TestHierarchicalLimiter 测试租户在自身限额内仍受全局上限约束
+func TestHierarchicalLimiter(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	hl := NewHierarchicalLimiter(
+		NewTokenBucketWithClock(5, 1, clock),
+		NewKeyedLimiterWithClock(3, 1, clock),
+	)
+
+	// 租户 a 用完自己的 3 个令牌
+	for i := 0; i < 3; i++ {
+		if !hl.Allow("a") {
+			t.Fatalf("a 的第 %d 个请求应该通过", i+1)
+		}
+	}
+	if hl.Allow("a") {
+		t.Error("a 超过租户限额应该被拒绝")
+	}
+
+	// 租户 b 只能用完全局剩下的 2 个令牌
+	if !hl.Allow("b") || !hl.Allow("b") {
+		t.Fatal("b 的前两个请求应该通过")
+	}
+	if hl.Allow("b") {
+		t.Error("b 在租户限额内但全局令牌耗尽, 应该被拒绝")
+	}
+
+	// 被全局拒绝的请求归还了租户令牌
+	if got := hl.tenants.Bucket("b").GetTokens(); got != 1 {
+		t.Errorf("被全局拒绝后 b 的令牌应归还, 剩余应为 1, 实际: %d", got)
+	}
+}
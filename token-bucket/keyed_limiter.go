@@ -0,0 +1,68 @@
+package tokenbucket
+
+import "sync"
+
+// KeyedLimiter 按 key（如客户端 IP、租户）分别限流的限流器
+// 每个 key 在第一次使用时创建独立的令牌桶，参数相同。
+// 令牌桶不会自动回收，key 数量无上限的场景需要调用方定期 Remove
+type KeyedLimiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*TokenBucket
+	capacity int   // 每个 key 令牌桶的容量
+	rate     int   // 每个 key 令牌桶的速率（每秒）
+	clock    Clock // 令牌桶使用的时间来源
+}
+
+// NewKeyedLimiter 创建一个按 key 限流的限流器
+// capacity: 每个 key 令牌桶的容量
+// rate: 每个 key 令牌桶的速率（每秒）
+func NewKeyedLimiter(capacity, rate int) *KeyedLimiter {
+	return NewKeyedLimiterWithClock(capacity, rate, realClock{})
+}
+
+// NewKeyedLimiterWithClock 使用指定的时间来源创建按 key 限流的限流器
+func NewKeyedLimiterWithClock(capacity, rate int, clock Clock) *KeyedLimiter {
+	return &KeyedLimiter{
+		buckets:  make(map[string]*TokenBucket),
+		capacity: capacity,
+		rate:     rate,
+		clock:    clock,
+	}
+}
+
+// Bucket 返回 key 对应的令牌桶，不存在时创建
+func (kl *KeyedLimiter) Bucket(key string) *TokenBucket {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+
+	tb, ok := kl.buckets[key]
+	if !ok {
+		tb = NewTokenBucketWithClock(kl.capacity, kl.rate, kl.clock)
+		kl.buckets[key] = tb
+	}
+	return tb
+}
+
+// TryConsume 尝试从 key 对应的令牌桶消费令牌
+func (kl *KeyedLimiter) TryConsume(key string, count int) bool {
+	return kl.Bucket(key).TryConsume(count)
+}
+
+// Allow 尝试从 key 对应的令牌桶消费 1 个令牌
+func (kl *KeyedLimiter) Allow(key string) bool {
+	return kl.TryConsume(key, 1)
+}
+
+// Remove 移除 key 对应的令牌桶
+func (kl *KeyedLimiter) Remove(key string) {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	delete(kl.buckets, key)
+}
+
+// Len 返回当前的 key 数量
+func (kl *KeyedLimiter) Len() int {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	return len(kl.buckets)
+}
@@ -0,0 +1,26 @@
+package tokenbucket
+
+import "testing"
+
+// TestKeyedLimiter 测试不同 key 独立限流
+func TestKeyedLimiter(t *testing.T) {
+	kl := NewKeyedLimiter(2, 1)
+
+	if !kl.Allow("a") || !kl.Allow("a") {
+		t.Fatal("a 的前两个请求应该通过")
+	}
+	if kl.Allow("a") {
+		t.Error("a 的令牌耗尽后应该被拒绝")
+	}
+	if !kl.Allow("b") {
+		t.Error("b 不应受 a 的影响")
+	}
+	if kl.Len() != 2 {
+		t.Errorf("应有 2 个 key, 实际: %d", kl.Len())
+	}
+
+	kl.Remove("a")
+	if !kl.Allow("a") {
+		t.Error("移除后重新创建的令牌桶应该是满的")
+	}
+}
@@ -0,0 +1,69 @@
+package tokenbucket
+
+import (
+	"sync"
+	"time"
+)
+
+// LeakyBucket 漏桶
+// 请求先进入桶中排队，再以固定速率流出：不论到达多么集中，流出的间隔都是 1/rate，
+// 桶中排队的量超过 capacity 时拒绝新的请求。
+// 与令牌桶允许突发不同，漏桶用于把输出整形为恒定速率
+type LeakyBucket struct {
+	mu       sync.Mutex
+	capacity int       // 桶中最多排队的量
+	rate     int       // 流出速率（每秒）
+	level    float64   // 当前排队的量
+	last     time.Time // 上次计算流出的时间
+	clock    Clock
+}
+
+// NewLeakyBucket 创建一个漏桶
+// capacity: 桶中最多排队的量
+// rate: 流出速率（每秒）
+func NewLeakyBucket(capacity, rate int) *LeakyBucket {
+	return NewLeakyBucketWithClock(capacity, rate, realClock{})
+}
+
+// NewLeakyBucketWithClock 使用指定的时间来源创建漏桶
+func NewLeakyBucketWithClock(capacity, rate int, clock Clock) *LeakyBucket {
+	return &LeakyBucket{
+		capacity: capacity,
+		rate:     rate,
+		last:     clock.Now(),
+		clock:    clock,
+	}
+}
+
+// Submit 提交 n 个单位
+// 桶中放得下时返回 true 和这 n 个单位全部流出还需等待的时间，调用方等待该时间后再发送；
+// 放不下时返回 false，不改变桶的状态
+func (lb *LeakyBucket) Submit(n int) (time.Duration, bool) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	lb.leak()
+	if lb.level+float64(n) > float64(lb.capacity) {
+		return 0, false
+	}
+	lb.level += float64(n)
+	return time.Duration(lb.level / float64(lb.rate) * float64(time.Second)), true
+}
+
+// Level 返回当前排队的量
+func (lb *LeakyBucket) Level() float64 {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.leak()
+	return lb.level
+}
+
+// leak 按流出速率减少排队的量，调用方需持有 lb.mu
+func (lb *LeakyBucket) leak() {
+	now := lb.clock.Now()
+	lb.level -= now.Sub(lb.last).Seconds() * float64(lb.rate)
+	if lb.level < 0 {
+		lb.level = 0
+	}
+	lb.last = now
+}
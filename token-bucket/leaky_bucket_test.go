@@ -0,0 +1,33 @@
+package tokenbucket
+
+import (
+	"testing"
+	"time"
+)
+
+// TestLeakyBucket 测试排队量按固定速率流出, 超过容量时拒绝
+func TestLeakyBucket(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	lb := NewLeakyBucketWithClock(5, 10, clock)
+
+	for i := 1; i <= 5; i++ {
+		delay, ok := lb.Submit(1)
+		if !ok {
+			t.Fatalf("第 %d 个应放入桶中", i)
+		}
+		if want := time.Duration(i) * 100 * time.Millisecond; delay != want {
+			t.Errorf("第 %d 个应等待 %v, 实际: %v", i, want, delay)
+		}
+	}
+	if _, ok := lb.Submit(1); ok {
+		t.Error("桶满时应拒绝")
+	}
+
+	clock.Advance(250 * time.Millisecond)
+	if level := lb.Level(); level != 2.5 {
+		t.Errorf("250ms 后应流出 2.5 个, 剩余: %.2f", level)
+	}
+	if _, ok := lb.Submit(2); !ok {
+		t.Error("流出后应能再放入 2 个")
+	}
+}
@@ -0,0 +1,51 @@
+package tokenbucket
+
+// Limiter 是限流器的通用接口
+// 调用方依赖该接口，通过配置在 TokenBucket 和 Unlimited 之间切换，
+// 而不需要在每个调用点判断是否开启限流
+type Limiter interface {
+	// TryConsume 尝试消费 n 个令牌，返回是否放行
+	TryConsume(n int) bool
+	// Allow 尝试消费 1 个令牌，返回是否放行
+	Allow() bool
+}
+
+// Reason 说明限流器放行或拒绝请求的原因，用于日志和监控
+type Reason string
+
+const (
+	ReasonOK                 Reason = "ok"                  // 放行
+	ReasonInsufficientTokens Reason = "insufficient_tokens" // 令牌不足
+	ReasonBreakerOpen        Reason = "breaker_open"        // 熔断器打开，或半开状态下探测请求尚未返回
+	ReasonPenalized          Reason = "penalized"           // key 处于惩罚期，见 PenaltyLimiter
+	ReasonQueueFull          Reason = "queue_full"          // 等待队列已满，见 QueuedLimiter
+	ReasonClosed             Reason = "closed"              // 限流器已关闭
+)
+
+// ReasonLimiter 是可以说明判定原因的限流器
+// 基础令牌桶只返回 ReasonOK 和 ReasonInsufficientTokens，组合限流器可以返回更具体的原因
+type ReasonLimiter interface {
+	// AllowWithReason 尝试消费 n 个令牌，返回是否放行及原因
+	AllowWithReason(n int) (bool, Reason)
+}
+
+var (
+	_ Limiter       = (*TokenBucket)(nil)
+	_ Limiter       = Unlimited{}
+	_ ReasonLimiter = (*TokenBucket)(nil)
+	_ ReasonLimiter = (*CompositeLimiter)(nil)
+	_ ReasonLimiter = (*QueuedLimiter)(nil)
+)
+
+// Unlimited 是不限流的 Limiter，总是放行
+type Unlimited struct{}
+
+// TryConsume 总是返回 true
+func (Unlimited) TryConsume(n int) bool {
+	return true
+}
+
+// Allow 总是返回 true
+func (Unlimited) Allow() bool {
+	return true
+}
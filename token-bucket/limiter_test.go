@@ -0,0 +1,106 @@
+package tokenbucket
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestUnlimited 测试 Unlimited 从不拒绝，而 TokenBucket 仍然限流
+func TestUnlimited(t *testing.T) {
+	limiters := map[string]Limiter{
+		"unlimited": Unlimited{},
+		"bucket":    NewTokenBucket(0, 0),
+	}
+
+	for i := 0; i < 1000; i++ {
+		if !limiters["unlimited"].Allow() || !limiters["unlimited"].TryConsume(1<<20) {
+			t.Fatal("Unlimited 不应拒绝任何请求")
+		}
+	}
+
+	if limiters["bucket"].Allow() {
+		t.Error("容量为 0 的 TokenBucket 应该拒绝请求")
+	}
+	if limiters["bucket"].TryConsume(1) {
+		t.Error("容量为 0 的 TokenBucket 应该拒绝请求")
+	}
+}
+
+// TestAllowWithReason 测试放行和拒绝时返回的原因与实际原因一致
+func TestAllowWithReason(t *testing.T) {
+	tb := NewTokenBucket(2, 0)
+	if ok, reason := tb.AllowWithReason(2); !ok || reason != ReasonOK {
+		t.Errorf("令牌充足时应放行且原因为 %s, 实际: %v, %s", ReasonOK, ok, reason)
+	}
+	if ok, reason := tb.AllowWithReason(1); ok || reason != ReasonInsufficientTokens {
+		t.Errorf("令牌不足时应拒绝且原因为 %s, 实际: %v, %s", ReasonInsufficientTokens, ok, reason)
+	}
+
+	cl := NewCompositeLimiter(NewTokenBucket(1, 0), 1, time.Hour)
+	if ok, reason := cl.AllowWithReason(1); !ok || reason != ReasonOK {
+		t.Errorf("组合限流器应放行, 实际: %v, %s", ok, reason)
+	}
+	if ok, reason := cl.AllowWithReason(1); ok || reason != ReasonInsufficientTokens {
+		t.Errorf("组合限流器令牌不足时原因应为 %s, 实际: %v, %s", ReasonInsufficientTokens, ok, reason)
+	}
+	cl.RecordFailure()
+	if ok, reason := cl.AllowWithReason(1); ok || reason != ReasonBreakerOpen {
+		t.Errorf("熔断器打开时原因应为 %s, 实际: %v, %s", ReasonBreakerOpen, ok, reason)
+	}
+}
+
+// TestPenaltyLimiterReason 测试惩罚期内的拒绝原因为 ReasonPenalized
+func TestPenaltyLimiterReason(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	pl := NewPenaltyLimiter(NewKeyedLimiterWithClock(1, 10, clock), 2, time.Minute)
+
+	if ok, reason := pl.AllowWithReason("key"); !ok || reason != ReasonOK {
+		t.Errorf("令牌充足时应放行, 实际: %v, %s", ok, reason)
+	}
+	for i := 0; i < 2; i++ {
+		if ok, reason := pl.AllowWithReason("key"); ok || reason != ReasonInsufficientTokens {
+			t.Errorf("令牌不足时原因应为 %s, 实际: %v, %s", ReasonInsufficientTokens, ok, reason)
+		}
+	}
+
+	// 令牌已恢复，但惩罚期内仍然拒绝
+	clock.Advance(time.Second)
+	if ok, reason := pl.AllowWithReason("key"); ok || reason != ReasonPenalized {
+		t.Errorf("惩罚期内原因应为 %s, 实际: %v, %s", ReasonPenalized, ok, reason)
+	}
+}
+
+// TestQueuedLimiterReason 测试排队限流器在队列满、有人排队和关闭时的拒绝原因
+func TestQueuedLimiterReason(t *testing.T) {
+	ql := NewQueuedLimiter(NewTokenBucket(1, 0), 1)
+
+	if ok, reason := ql.AllowWithReason(1); !ok || reason != ReasonOK {
+		t.Errorf("队列为空且令牌充足时应放行, 实际: %v, %s", ok, reason)
+	}
+	if ok, reason := ql.AllowWithReason(1); ok || reason != ReasonInsufficientTokens {
+		t.Errorf("令牌不足时原因应为 %s, 实际: %v, %s", ReasonInsufficientTokens, ok, reason)
+	}
+
+	// 速率为 0，排队者一直占用唯一的队列名额
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ql.Acquire(ctx) }()
+	deadline := time.Now().Add(time.Second)
+	for {
+		if ok, reason := ql.AllowWithReason(1); !ok && reason == ReasonQueueFull {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("有调用者占满队列时原因应为 %s", ReasonQueueFull)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	ql.Close()
+	if ok, reason := ql.AllowWithReason(1); ok || reason != ReasonClosed {
+		t.Errorf("关闭后原因应为 %s, 实际: %v, %s", ReasonClosed, ok, reason)
+	}
+}
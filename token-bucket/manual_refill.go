@@ -0,0 +1,51 @@
+package tokenbucket
+
+// ManualRefillBucket 不按时间补充、由外部事件注入令牌的令牌桶
+// 适用于按事件而不是按时间发放配额的场景，如每完成一个上游任务发放一个令牌。
+// 消费的逻辑与 TokenBucket 相同，只是令牌的来源从时间换成了 AddTokens
+type ManualRefillBucket struct {
+	tb *TokenBucket
+}
+
+var _ Limiter = (*ManualRefillBucket)(nil)
+
+// NewManualRefillBucket 创建一个由外部注入令牌的令牌桶，初始时桶为空
+func NewManualRefillBucket(capacity int) *ManualRefillBucket {
+	// 速率为 0 时 refill 不补充任何令牌
+	tb := NewTokenBucket(capacity, 0)
+	tb.tokens = 0
+	tb.minTokens, tb.maxTokens = 0, 0
+	return &ManualRefillBucket{tb: tb}
+}
+
+// AddTokens 注入 n 个令牌，超过容量的部分丢弃；n 不大于 0 时不做任何事
+func (b *ManualRefillBucket) AddTokens(n int) {
+	if n <= 0 {
+		return
+	}
+	tb := b.tb
+	tb.lock()
+	defer tb.unlock()
+
+	added := min(n, tb.capacity-tb.tokens)
+	if added <= 0 {
+		return
+	}
+	tb.tokens += added
+	tb.granted += int64(added)
+}
+
+// TryConsume 尝试消费 count 个令牌
+func (b *ManualRefillBucket) TryConsume(count int) bool {
+	return b.tb.TryConsume(count)
+}
+
+// Allow 尝试消费 1 个令牌
+func (b *ManualRefillBucket) Allow() bool {
+	return b.tb.TryConsume(1)
+}
+
+// Tokens 返回当前令牌数
+func (b *ManualRefillBucket) Tokens() int {
+	return b.tb.GetTokens()
+}
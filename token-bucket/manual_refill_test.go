@@ -0,0 +1,36 @@
+package tokenbucket
+
+import (
+	"testing"
+	"time"
+)
+
+// TestManualRefillBucket 测试不会按时间补充令牌，且注入的令牌不超过容量
+func TestManualRefillBucket(t *testing.T) {
+	b := NewManualRefillBucket(5)
+	if b.Allow() {
+		t.Error("初始时桶为空, 消费应失败")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if tokens := b.Tokens(); tokens != 0 {
+		t.Errorf("不应按时间补充令牌, 实际: %d", tokens)
+	}
+
+	b.AddTokens(3)
+	if !b.TryConsume(2) {
+		t.Error("注入 3 个令牌后消费 2 个应成功")
+	}
+	if b.TryConsume(2) {
+		t.Error("只剩 1 个令牌, 消费 2 个应失败")
+	}
+
+	b.AddTokens(100)
+	if tokens := b.Tokens(); tokens != 5 {
+		t.Errorf("注入的令牌不应超过容量 5, 实际: %d", tokens)
+	}
+	b.AddTokens(-1)
+	if !b.TryConsume(5) || b.Allow() {
+		t.Error("应正好可以消费容量 5 个令牌")
+	}
+}
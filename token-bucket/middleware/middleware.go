@@ -0,0 +1,34 @@
+// Package middleware 提供基于令牌桶的 net/http 限流中间件
+// 单独成包以避免核心令牌桶依赖 net/http
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	tokenbucket "example.com/go-examples/algorithm/token-bucket"
+)
+
+// Middleware 使用令牌桶对 next 进行限流
+// 每个请求消费 1 个令牌，被限流时返回 429，并通过 Retry-After 头告知客户端需等待的秒数
+func Middleware(tb *tokenbucket.TokenBucket, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !tb.TryConsume(1) {
+			w.Header().Set("Retry-After", retryAfter(tb.TimeUntilAvailable(1)))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// retryAfter 将等待时间转换为 Retry-After 的秒数，向上取整且至少为 1 秒
+func retryAfter(wait time.Duration) string {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tokenbucket "example.com/go-examples/algorithm/token-bucket"
+)
+
+// TestMiddleware 测试突发请求被限流并设置 Retry-After
+func TestMiddleware(t *testing.T) {
+	tb := tokenbucket.NewTokenBucket(3, 1)
+	handler := Middleware(tb, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	passed, limited := 0, 0
+	for i := 0; i < 5; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		switch rec.Code {
+		case http.StatusOK:
+			passed++
+		case http.StatusTooManyRequests:
+			limited++
+			if got := rec.Header().Get("Retry-After"); got != "1" {
+				t.Errorf("Retry-After 期望为 1, 实际: %q", got)
+			}
+		default:
+			t.Fatalf("意外的状态码: %d", rec.Code)
+		}
+	}
+
+	if passed != 3 || limited != 2 {
+		t.Errorf("期望通过 3 个、限流 2 个, 实际通过 %d 个、限流 %d 个", passed, limited)
+	}
+}
@@ -0,0 +1,82 @@
+package tokenbucket
+
+import (
+	"sync"
+	"time"
+)
+
+// PenaltyLimiter 带惩罚期的按 key 限流器
+// 某个 key 连续被拒绝达到阈值后进入惩罚期，期间无论令牌是否充足都拒绝该 key，
+// 用于封禁持续超限的滥用方
+type PenaltyLimiter struct {
+	mu        sync.Mutex
+	limiter   *KeyedLimiter
+	threshold int                  // 触发惩罚的连续拒绝次数
+	cooldown  time.Duration        // 惩罚期时长
+	rejects   map[string]int       // 每个 key 的连续拒绝次数
+	until     map[string]time.Time // 处于惩罚期的 key 及其结束时间
+}
+
+// NewPenaltyLimiter 创建一个带惩罚期的限流器
+// threshold: 连续被拒绝多少次后进入惩罚期
+// cooldown: 惩罚期时长，使用 limiter 的时间来源计时
+func NewPenaltyLimiter(limiter *KeyedLimiter, threshold int, cooldown time.Duration) *PenaltyLimiter {
+	return &PenaltyLimiter{
+		limiter:   limiter,
+		threshold: threshold,
+		cooldown:  cooldown,
+		rejects:   make(map[string]int),
+		until:     make(map[string]time.Time),
+	}
+}
+
+// Allow 判断 key 的请求是否放行
+// 惩罚期内直接拒绝且不消费令牌；放行后连续拒绝次数清零
+func (pl *PenaltyLimiter) Allow(key string) bool {
+	ok, _ := pl.AllowWithReason(key)
+	return ok
+}
+
+// AllowWithReason 类似于 Allow，但同时返回判定原因
+// 惩罚期内拒绝时为 ReasonPenalized，令牌不足时为 ReasonInsufficientTokens
+func (pl *PenaltyLimiter) AllowWithReason(key string) (bool, Reason) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	if pl.penalizedLocked(key) {
+		return false, ReasonPenalized
+	}
+
+	if pl.limiter.Allow(key) {
+		delete(pl.rejects, key)
+		return true, ReasonOK
+	}
+
+	pl.rejects[key]++
+	if pl.rejects[key] >= pl.threshold {
+		delete(pl.rejects, key)
+		pl.until[key] = pl.limiter.clock.Now().Add(pl.cooldown)
+	}
+	return false, ReasonInsufficientTokens
+}
+
+// IsPenalized 判断 key 当前是否处于惩罚期
+func (pl *PenaltyLimiter) IsPenalized(key string) bool {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	return pl.penalizedLocked(key)
+}
+
+// penalizedLocked 判断 key 是否处于惩罚期，惩罚期结束时清除记录
+// 调用方需持有 pl.mu
+func (pl *PenaltyLimiter) penalizedLocked(key string) bool {
+	until, ok := pl.until[key]
+	if !ok {
+		return false
+	}
+	if pl.limiter.clock.Now().Before(until) {
+		return true
+	}
+	delete(pl.until, key)
+	return false
+}
@@ -0,0 +1,55 @@
+package tokenbucket
+
+import (
+	"testing"
+	"time"
+)
+
+// TestPenaltyLimiter 测试连续拒绝触发惩罚期，惩罚期结束后恢复
+func TestPenaltyLimiter(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	pl := NewPenaltyLimiter(NewKeyedLimiterWithClock(2, 10, clock), 3, time.Minute)
+
+	pl.Allow("abuser")
+	pl.Allow("abuser")
+	for i := 0; i < 3; i++ {
+		if pl.Allow("abuser") {
+			t.Fatal("令牌耗尽后应该被拒绝")
+		}
+	}
+	if !pl.IsPenalized("abuser") {
+		t.Fatal("连续被拒绝 3 次后应进入惩罚期")
+	}
+	if pl.IsPenalized("other") || !pl.Allow("other") {
+		t.Error("其他 key 不应受影响")
+	}
+
+	// 令牌已恢复，但惩罚期内仍然拒绝
+	clock.Advance(time.Second)
+	if pl.Allow("abuser") {
+		t.Error("惩罚期内即使令牌充足也应拒绝")
+	}
+
+	clock.Advance(time.Minute)
+	if pl.IsPenalized("abuser") {
+		t.Error("惩罚期结束后应解除")
+	}
+	if !pl.Allow("abuser") {
+		t.Error("惩罚期结束后应该放行")
+	}
+}
+
+// TestPenaltyLimiterResetOnSuccess 测试放行会清零连续拒绝次数
+func TestPenaltyLimiterResetOnSuccess(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	pl := NewPenaltyLimiter(NewKeyedLimiterWithClock(1, 10, clock), 3, time.Minute)
+
+	for i := 0; i < 5; i++ {
+		pl.Allow("key")
+		pl.Allow("key")
+		clock.Advance(100 * time.Millisecond)
+	}
+	if pl.IsPenalized("key") {
+		t.Error("拒绝之间有放行, 不应进入惩罚期")
+	}
+}
@@ -0,0 +1,104 @@
+package tokenbucket
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// PriorityLimiter 按优先级分配令牌的限流器
+//
+// 没有请求排队且令牌充足时立即放行，不等待窗口；只有令牌不足时请求才进入批处理窗口。
+//
+// 公平性保证：同一个批处理窗口内到达的请求按优先级从高到低依次尝试消费令牌，
+// 优先级相同的按到达顺序处理，低优先级请求只能拿到高优先级请求剩下的令牌。
+// 窗口开启后到达的请求即使令牌充足也要排队，不会越过窗口内等待的高优先级请求。
+// 不同窗口之间不做补偿，持续的高优先级流量可能使低优先级请求一直被拒绝。
+type PriorityLimiter struct {
+	tb      *TokenBucket
+	window  time.Duration // 批处理窗口
+	mu      sync.Mutex
+	pending priorityQueue // 当前窗口内等待分配的请求
+	seq     int           // 到达序号，用于同优先级请求的排序
+}
+
+// priorityRequest 表示一个等待分配令牌的请求
+type priorityRequest struct {
+	priority int
+	count    int
+	seq      int
+	result   chan bool
+}
+
+// priorityQueue 按优先级从高到低、到达顺序从早到晚排列的堆
+type priorityQueue []*priorityRequest
+
+func (q priorityQueue) Len() int { return len(q) }
+
+func (q priorityQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q priorityQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *priorityQueue) Push(x interface{}) { *q = append(*q, x.(*priorityRequest)) }
+
+func (q *priorityQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
+
+// NewPriorityLimiter 创建一个按优先级分配令牌的限流器
+// window: 批处理窗口，窗口内到达的请求一起按优先级分配令牌
+func NewPriorityLimiter(tb *TokenBucket, window time.Duration) *PriorityLimiter {
+	return &PriorityLimiter{
+		tb:     tb,
+		window: window,
+	}
+}
+
+// TryConsume 以指定优先级尝试消费令牌
+// 没有请求排队且令牌充足时立即返回 true；否则等待当前窗口结束后统一分配，最长阻塞一个窗口
+// priority: 优先级，数值越大越优先
+// count: 需要消费的令牌数
+func (pl *PriorityLimiter) TryConsume(priority, count int) bool {
+	req := &priorityRequest{
+		priority: priority,
+		count:    count,
+		result:   make(chan bool, 1),
+	}
+
+	pl.mu.Lock()
+	if pl.pending.Len() == 0 {
+		// 没有竞争，直接消费
+		if pl.tb.TryConsume(count) {
+			pl.mu.Unlock()
+			return true
+		}
+		// 令牌不足，第一个排队的请求负责开启窗口
+		time.AfterFunc(pl.window, pl.drain)
+	}
+	req.seq = pl.seq
+	pl.seq++
+	heap.Push(&pl.pending, req)
+	pl.mu.Unlock()
+
+	return <-req.result
+}
+
+// drain 窗口结束时按优先级依次分配令牌
+func (pl *PriorityLimiter) drain() {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	for pl.pending.Len() > 0 {
+		req := heap.Pop(&pl.pending).(*priorityRequest)
+		req.result <- pl.tb.TryConsume(req.count)
+	}
+}
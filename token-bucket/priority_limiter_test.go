@@ -0,0 +1,65 @@
+package tokenbucket
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestPriorityLimiter 测试令牌不足时高优先级请求优先获得令牌
+func TestPriorityLimiter(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	tb := NewTokenBucketWithClock(3, 1, clock)
+	tb.Drain()
+	pl := NewPriorityLimiter(tb, 100*time.Millisecond)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	granted := make(map[int]int)
+
+	// 先到达的低优先级请求与后到达的高优先级请求在同一窗口内竞争
+	for _, priority := range []int{1, 1, 1, 10, 10, 10} {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			if pl.TryConsume(p, 1) {
+				mu.Lock()
+				granted[p]++
+				mu.Unlock()
+			}
+		}(priority)
+		time.Sleep(time.Millisecond)
+	}
+	// 窗口结束前补充 3 个令牌
+	clock.Advance(3 * time.Second)
+	wg.Wait()
+
+	if granted[10] != 3 {
+		t.Errorf("高优先级请求应该全部通过, 实际通过: %d", granted[10])
+	}
+	if granted[1] != 0 {
+		t.Errorf("低优先级请求应该全部被拒绝, 实际通过: %d", granted[1])
+	}
+}
+
+// TestPriorityLimiterUncontended 测试没有竞争且令牌充足时立即放行
+func TestPriorityLimiterUncontended(t *testing.T) {
+	pl := NewPriorityLimiter(NewTokenBucket(3, 1), time.Hour)
+
+	done := make(chan bool, 3)
+	go func() {
+		for i := 0; i < 3; i++ {
+			done <- pl.TryConsume(1, 1)
+		}
+	}()
+	for i := 0; i < 3; i++ {
+		select {
+		case ok := <-done:
+			if !ok {
+				t.Fatal("令牌充足时应该放行")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("没有竞争时不应等待窗口")
+		}
+	}
+}
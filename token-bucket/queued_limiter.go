@@ -0,0 +1,177 @@
+package tokenbucket
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrQueueFull 表示 QueuedLimiter 的等待队列已满
+var ErrQueueFull = errors.New("tokenbucket: queue full")
+
+// ErrLimiterClosed 表示 QueuedLimiter 已经关闭
+var ErrLimiterClosed = errors.New("tokenbucket: limiter closed")
+
+// QueuedLimiter 按到达顺序排队发放令牌的限流器
+// 调用者在有界队列中排队，令牌补充后严格按先来先得的顺序发放，
+// 不会出现多个等待者同时醒来争抢令牌、后到者先得的情况
+type QueuedLimiter struct {
+	tb       *TokenBucket
+	queue    chan *ticket
+	maxQueue int // 最多排队的调用者数量
+
+	mu      sync.Mutex
+	waiting int  // 占用队列名额的 ticket 数量（包括正在等待令牌的队首），发放 goroutine 处理完才减少
+	closed  bool // 是否已关闭
+
+	ctx    context.Context // Close 时取消，结束发放
+	cancel context.CancelFunc
+	exited chan struct{} // 发放 goroutine 退出时关闭
+}
+
+// ticket 是一个排队的调用者
+type ticket struct {
+	ctx   context.Context
+	ready chan struct{} // 获得令牌或限流器关闭时关闭
+	err   error         // 关闭 ready 前设置，非 nil 表示没有获得令牌
+}
+
+// NewQueuedLimiter 创建一个排队限流器
+// tb: 令牌来源
+// maxQueue: 最多排队的调用者数量，超过时 Acquire 直接返回 ErrQueueFull
+// 创建后会启动一个发放令牌的 goroutine，不再使用时需要调用 Close
+func NewQueuedLimiter(tb *TokenBucket, maxQueue int) *QueuedLimiter {
+	ctx, cancel := context.WithCancel(context.Background())
+	ql := &QueuedLimiter{
+		tb:       tb,
+		queue:    make(chan *ticket, maxQueue),
+		maxQueue: maxQueue,
+		ctx:      ctx,
+		cancel:   cancel,
+		exited:   make(chan struct{}),
+	}
+	go ql.dispatch()
+	return ql
+}
+
+// Acquire 排队等待 1 个令牌
+// 队列已满返回 ErrQueueFull，限流器已关闭返回 ErrLimiterClosed，
+// ctx 结束时离开队列并返回 ctx.Err()；离开者的 ticket 在轮到它时被跳过，不消费令牌，此前仍占用队列名额
+func (ql *QueuedLimiter) Acquire(ctx context.Context) error {
+	t := &ticket{ctx: ctx, ready: make(chan struct{})}
+
+	ql.mu.Lock()
+	if ql.closed {
+		ql.mu.Unlock()
+		return ErrLimiterClosed
+	}
+	if ql.waiting >= ql.maxQueue {
+		ql.mu.Unlock()
+		return ErrQueueFull
+	}
+	// waiting 不小于 channel 中的 ticket 数量（已离开的调用者的 ticket 在处理完之前仍占用名额），
+	// 不超过 channel 的容量，发送不会阻塞；仍使用非阻塞发送，避免持有锁时阻塞
+	select {
+	case ql.queue <- t:
+		ql.waiting++
+	default:
+		ql.mu.Unlock()
+		return ErrQueueFull
+	}
+	ql.mu.Unlock()
+
+	select {
+	case <-t.ready:
+		return t.err
+	case <-ctx.Done():
+		// 令牌可能恰好已经发放，优先使用
+		select {
+		case <-t.ready:
+			return t.err
+		default:
+			return ctx.Err()
+		}
+	}
+}
+
+// AllowWithReason 不排队地尝试消费 n 个令牌，返回是否放行及原因
+// 为了不让后到者插队，只有队列为空时才直接从令牌桶消费：
+// 队列已满时为 ReasonQueueFull，有调用者排队（说明令牌不足）时为 ReasonInsufficientTokens，
+// 限流器已关闭时为 ReasonClosed
+func (ql *QueuedLimiter) AllowWithReason(n int) (bool, Reason) {
+	ql.mu.Lock()
+	defer ql.mu.Unlock()
+
+	switch {
+	case ql.closed:
+		return false, ReasonClosed
+	case ql.waiting >= ql.maxQueue:
+		return false, ReasonQueueFull
+	case ql.waiting > 0:
+		return false, ReasonInsufficientTokens
+	}
+	return ql.tb.AllowWithReason(n)
+}
+
+// Close 关闭限流器，排队中的调用者收到 ErrLimiterClosed
+func (ql *QueuedLimiter) Close() {
+	ql.mu.Lock()
+	ql.closed = true
+	ql.mu.Unlock()
+
+	ql.cancel()
+	<-ql.exited
+}
+
+// dispatch 按排队顺序逐个等待令牌并发放
+func (ql *QueuedLimiter) dispatch() {
+	defer close(ql.exited)
+
+	for {
+		select {
+		case t := <-ql.queue:
+			ql.serve(t)
+			ql.release()
+		case <-ql.ctx.Done():
+			// Close 设置 closed 之后不会再有新的调用者入队
+			for {
+				select {
+				case t := <-ql.queue:
+					t.err = ErrLimiterClosed
+					close(t.ready)
+					ql.release()
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// release 在处理完一个 ticket 后释放它占用的队列名额
+func (ql *QueuedLimiter) release() {
+	ql.mu.Lock()
+	ql.waiting--
+	ql.mu.Unlock()
+}
+
+// serve 为队首的调用者等待令牌，调用者已离开时跳过
+func (ql *QueuedLimiter) serve(t *ticket) {
+	// 先检查调用者是否已离开，否则令牌充足时 TryConsumeContext 会先消费一个无人领取的令牌
+	if t.ctx.Err() != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(t.ctx)
+	stop := context.AfterFunc(ql.ctx, cancel)
+	ok := ql.tb.TryConsumeContext(ctx, 1)
+	stop()
+	cancel()
+
+	switch {
+	case ok:
+		close(t.ready)
+	case ql.ctx.Err() != nil:
+		t.err = ErrLimiterClosed
+		close(t.ready)
+	}
+}
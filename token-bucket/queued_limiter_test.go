@@ -0,0 +1,171 @@
+package tokenbucket
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestQueuedLimiterFIFO 测试令牌按排队顺序发放
+func TestQueuedLimiterFIFO(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	tb := NewTokenBucketWithClock(1, 100, clock)
+	tb.Drain()
+	ql := NewQueuedLimiter(tb, 10)
+	defer ql.Close()
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if err := ql.Acquire(context.Background()); err != nil {
+				t.Errorf("调用者 %d 获取令牌失败: %v", id, err)
+				return
+			}
+			mu.Lock()
+			order = append(order, id)
+			mu.Unlock()
+		}(i)
+		waitQueued(t, ql, i+1)
+	}
+
+	// 所有调用者排队后每次只补充 1 个令牌, 等它被领取后再补充下一个
+	for i := 0; i < 5; i++ {
+		clock.Advance(10 * time.Millisecond)
+		deadline := time.Now().Add(time.Second)
+		for {
+			mu.Lock()
+			n := len(order)
+			mu.Unlock()
+			if n == i+1 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("等待第 %d 个令牌被领取超时", i+1)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	wg.Wait()
+
+	for i, id := range order {
+		if id != i {
+			t.Fatalf("应按排队顺序获得令牌, 实际顺序: %v", order)
+		}
+	}
+}
+
+// TestQueuedLimiterFull 测试队列已满时拒绝新的调用者
+func TestQueuedLimiterFull(t *testing.T) {
+	tb := NewTokenBucketWithClock(1, 1, NewManualClock(time.Now()))
+	tb.Drain()
+	ql := NewQueuedLimiter(tb, 2)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- ql.Acquire(context.Background())
+		}()
+		waitQueued(t, ql, i+1)
+	}
+
+	if err := ql.Acquire(context.Background()); err != ErrQueueFull {
+		t.Errorf("队列已满时期望 ErrQueueFull, 实际: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := ql.Acquire(ctx); err != ErrQueueFull {
+		t.Errorf("队列已满时即使带超时也应直接拒绝, 实际: %v", err)
+	}
+
+	ql.Close()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != ErrLimiterClosed {
+			t.Errorf("关闭后排队的调用者期望 ErrLimiterClosed, 实际: %v", err)
+		}
+	}
+	if err := ql.Acquire(context.Background()); err != ErrLimiterClosed {
+		t.Errorf("关闭后期望 ErrLimiterClosed, 实际: %v", err)
+	}
+}
+
+// waitQueued 等待排队人数达到 n
+func waitQueued(t *testing.T, ql *QueuedLimiter, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		ql.mu.Lock()
+		waiting := ql.waiting
+		ql.mu.Unlock()
+		if waiting >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("等待 %d 个调用者排队超时, 当前: %d", n, waiting)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestQueuedLimiterLeftTicket 测试离开的调用者在其 ticket 处理完之前仍占用名额,
+// 新的调用者直接收到 ErrQueueFull, 而不是在持有锁时阻塞在已满的队列上
+func TestQueuedLimiterLeftTicket(t *testing.T) {
+	tb := NewTokenBucketWithClock(1, 1, NewManualClock(time.Now()))
+	tb.Drain()
+	ql := NewQueuedLimiter(tb, 2)
+
+	first := make(chan error, 1)
+	go func() {
+		first <- ql.Acquire(context.Background())
+	}()
+	waitQueued(t, ql, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := ql.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("超时离开应返回 DeadlineExceeded, 实际: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- ql.Acquire(context.Background())
+	}()
+	select {
+	case err := <-done:
+		if err != ErrQueueFull {
+			t.Errorf("离开者的 ticket 仍占用名额, 期望 ErrQueueFull, 实际: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Acquire 阻塞在已满的队列上")
+	}
+
+	ql.Close()
+	if err := <-first; err != ErrLimiterClosed {
+		t.Errorf("关闭后排队的调用者期望 ErrLimiterClosed, 实际: %v", err)
+	}
+}
+
+// TestQueuedLimiterSkipsLeft 测试轮到已离开的调用者时跳过, 不消费令牌
+func TestQueuedLimiterSkipsLeft(t *testing.T) {
+	tb := NewTokenBucketWithClock(1, 1, NewManualClock(time.Now()))
+	ql := NewQueuedLimiter(tb, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := ql.Acquire(ctx)
+	// 等待发放 goroutine 处理完该 ticket
+	ql.Close()
+
+	if err == nil {
+		// 令牌恰好在调用者离开前发放, 调用者拿到了令牌
+		return
+	}
+	if tokens := tb.GetTokens(); tokens != 1 {
+		t.Errorf("已离开的调用者不应消费令牌, 剩余令牌期望 1, 实际: %d", tokens)
+	}
+}
@@ -0,0 +1,111 @@
+package tokenbucket
+
+import (
+	"sync"
+	"time"
+)
+
+// Sample 是 Recorder 记录的一个采样点
+type Sample struct {
+	Time   time.Time // 采样时间（令牌桶时间来源的时间）
+	Tokens int       // 当时的令牌数
+}
+
+// Recorder 定期采样令牌桶的令牌数，保存最近的若干个采样点
+// 用于压测时绘制令牌数随时间的变化，观察突发后的恢复过程
+type Recorder struct {
+	tb       *TokenBucket
+	interval time.Duration // 采样间隔
+
+	mu      sync.Mutex
+	samples []Sample // 环形缓冲区
+	next    int      // 下一个写入位置
+	full    bool     // 缓冲区是否已写满过
+
+	stop chan struct{} // 非 nil 表示采样 goroutine 正在运行
+	done chan struct{} // 采样 goroutine 退出时关闭
+}
+
+// NewRecorder 创建一个采样记录器
+// interval: Start 后的采样间隔
+// size: 最多保留的采样点数量，写满后覆盖最旧的采样点
+func NewRecorder(tb *TokenBucket, interval time.Duration, size int) *Recorder {
+	return &Recorder{
+		tb:       tb,
+		interval: interval,
+		samples:  make([]Sample, size),
+	}
+}
+
+// Start 启动采样 goroutine，每隔 interval 调用一次 Sample
+// 采样间隔使用真实计时器；使用 ManualClock 时可以不调用 Start，
+// 在推进时钟后直接调用 Sample 得到确定的结果
+func (r *Recorder) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stop != nil {
+		return
+	}
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.run(r.stop, r.done)
+}
+
+// Stop 停止采样 goroutine 并等待其退出
+func (r *Recorder) Stop() {
+	r.mu.Lock()
+	stop, done := r.stop, r.done
+	r.stop, r.done = nil, nil
+	r.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+func (r *Recorder) run(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.Sample()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Sample 立即记录一个采样点
+func (r *Recorder) Sample() {
+	s := Sample{Time: r.tb.Clock().Now(), Tokens: r.tb.GetTokens()}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.samples) == 0 {
+		return
+	}
+	r.samples[r.next] = s
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Samples 按时间顺序返回保留的采样点
+func (r *Recorder) Samples() []Sample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]Sample(nil), r.samples[:r.next]...)
+	}
+	out := make([]Sample, 0, len(r.samples))
+	out = append(out, r.samples[r.next:]...)
+	return append(out, r.samples[:r.next]...)
+}
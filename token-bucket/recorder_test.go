@@ -0,0 +1,63 @@
+package tokenbucket
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRecorderBurstRecovery 测试记录的序列先耗尽再线性恢复
+func TestRecorderBurstRecovery(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	tb := NewTokenBucketWithClock(10, 2, clock)
+	r := NewRecorder(tb, time.Second, 8)
+
+	r.Sample()
+	tb.TryConsume(10) // 突发耗尽
+	for i := 0; i < 7; i++ {
+		r.Sample()
+		clock.Advance(time.Second)
+	}
+
+	var got []int
+	for _, s := range r.Samples() {
+		got = append(got, s.Tokens)
+	}
+	want := []int{10, 0, 2, 4, 6, 8, 10, 10}
+	if len(got) != len(want) {
+		t.Fatalf("采样点数量不符, 实际: %v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("采样序列应先耗尽再线性恢复, 实际: %v, 期望: %v", got, want)
+		}
+	}
+
+	// 写满后覆盖最旧的采样点, 仍按时间顺序返回
+	r.Sample()
+	samples := r.Samples()
+	if len(samples) != 8 || samples[0].Tokens != 0 {
+		t.Errorf("写满后应丢弃最旧的采样点, 实际: %+v", samples)
+	}
+	for i := 1; i < len(samples); i++ {
+		if samples[i].Time.Before(samples[i-1].Time) {
+			t.Errorf("采样点应按时间顺序返回: %+v", samples)
+		}
+	}
+}
+
+// TestRecorderStartStop 测试采样 goroutine 按间隔采样并能停止
+func TestRecorderStartStop(t *testing.T) {
+	r := NewRecorder(NewTokenBucket(10, 1), time.Millisecond, 1000)
+	r.Start()
+	time.Sleep(20 * time.Millisecond)
+	r.Stop()
+
+	n := len(r.Samples())
+	if n == 0 {
+		t.Fatal("启动后应该有采样点")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if len(r.Samples()) != n {
+		t.Error("停止后不应再采样")
+	}
+}
@@ -0,0 +1,86 @@
+package tokenbucket
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Registry 令牌桶注册表，用于统一导出进程内所有限流器的指标
+type Registry struct {
+	mu      sync.Mutex
+	buckets map[string]*TokenBucket
+}
+
+// DefaultRegistry 默认注册表，NewRegisteredTokenBucket 创建的令牌桶注册到这里
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry 创建一个空的注册表
+func NewRegistry() *Registry {
+	return &Registry{buckets: make(map[string]*TokenBucket)}
+}
+
+// NewRegisteredTokenBucket 创建一个令牌桶并以 name 注册到 DefaultRegistry
+func NewRegisteredTokenBucket(name string, capacity, rate int) *TokenBucket {
+	tb := NewTokenBucket(capacity, rate)
+	DefaultRegistry.Register(name, tb)
+	return tb
+}
+
+// Register 以 name 注册令牌桶，同名的令牌桶会被替换
+func (r *Registry) Register(name string, tb *TokenBucket) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buckets[name] = tb
+}
+
+// Unregister 移除以 name 注册的令牌桶
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.buckets, name)
+}
+
+// WriteText 以 Prometheus 文本格式输出所有令牌桶的指标，按名称排序
+// 每个令牌桶输出容量、速率、当前令牌数、放行次数和限流次数，例如：
+//
+//	token_bucket_capacity{name="api"} 10
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.buckets))
+	for name := range r.buckets {
+		names = append(names, name)
+	}
+	buckets := make(map[string]*TokenBucket, len(r.buckets))
+	for name, tb := range r.buckets {
+		buckets[name] = tb
+	}
+	r.mu.Unlock()
+
+	sort.Strings(names)
+	for _, name := range names {
+		tb := buckets[name]
+
+		tb.lock()
+		tb.refill()
+		metrics := []struct {
+			metric string
+			value  int64
+		}{
+			{"token_bucket_capacity", int64(tb.capacity)},
+			{"token_bucket_rate", int64(tb.rate)},
+			{"token_bucket_tokens", int64(tb.tokens)},
+			{"token_bucket_allowed_total", tb.allowed},
+			{"token_bucket_rejected_total", tb.rejected},
+		}
+		tb.unlock()
+
+		for _, m := range metrics {
+			if _, err := fmt.Fprintf(w, "%s{name=%q} %d\n", m.metric, name, m.value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package tokenbucket
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRegistryWriteText 测试注册表导出指标
+func TestRegistryWriteText(t *testing.T) {
+	api := NewRegisteredTokenBucket("test_api", 10, 5)
+	login := NewRegisteredTokenBucket("test_login", 2, 1)
+	defer DefaultRegistry.Unregister("test_api")
+	defer DefaultRegistry.Unregister("test_login")
+
+	api.TryConsume(3)
+	login.TryConsume(2)
+	login.TryConsume(1)
+
+	var sb strings.Builder
+	if err := DefaultRegistry.WriteText(&sb); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+	out := sb.String()
+
+	expected := []string{
+		`token_bucket_capacity{name="test_api"} 10`,
+		`token_bucket_rate{name="test_api"} 5`,
+		`token_bucket_tokens{name="test_api"} 7`,
+		`token_bucket_allowed_total{name="test_api"} 1`,
+		`token_bucket_rejected_total{name="test_api"} 0`,
+		`token_bucket_capacity{name="test_login"} 2`,
+		`token_bucket_rate{name="test_login"} 1`,
+		`token_bucket_tokens{name="test_login"} 0`,
+		`token_bucket_allowed_total{name="test_login"} 1`,
+		`token_bucket_rejected_total{name="test_login"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("输出中缺少: %s\n实际输出:\n%s", line, out)
+		}
+	}
+}
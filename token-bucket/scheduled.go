@@ -0,0 +1,92 @@
+package tokenbucket
+
+import (
+	"sync"
+	"time"
+)
+
+// RateWindow 一天中的一个时间段及其速率
+// Start、End 是距当天零点的偏移，区间为 [Start, End)；
+// Start 大于 End 表示跨过零点，如 22:00 到次日 06:00
+type RateWindow struct {
+	Start time.Duration
+	End   time.Duration
+	Rate  int // 该时间段的令牌生成速率（每秒）
+}
+
+// contains 判断距零点的偏移 offset 是否落在时间段内
+func (w RateWindow) contains(offset time.Duration) bool {
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// ScheduledLimiter 按一天中的时间段切换速率的限流器，如闲时放开更高的速率
+// 时间段重叠时使用 windows 中靠前的一个，不在任何时间段内时使用 defaultRate。
+// 时间取自令牌桶的时钟，按时钟返回时间所在的时区计算一天中的时刻。
+// 速率在每次调用时检查并切换，切换前按旧速率结算已累积的令牌
+type ScheduledLimiter struct {
+	tb          *TokenBucket
+	mu          sync.Mutex
+	windows     []RateWindow
+	defaultRate int
+	rate        int // 令牌桶当前使用的速率
+}
+
+// NewScheduledLimiter 创建一个按时间段切换速率的限流器
+// tb 的容量保持不变，速率由当前所在的时间段决定
+func NewScheduledLimiter(tb *TokenBucket, defaultRate int, windows []RateWindow) *ScheduledLimiter {
+	sl := &ScheduledLimiter{
+		tb:          tb,
+		windows:     append([]RateWindow(nil), windows...),
+		defaultRate: defaultRate,
+		rate:        tb.Rate(),
+	}
+	sl.update()
+	return sl
+}
+
+// TryConsume 按当前时间段的速率尝试消费令牌
+func (sl *ScheduledLimiter) TryConsume(n int) bool {
+	sl.update()
+	return sl.tb.TryConsume(n)
+}
+
+// Allow 尝试消费 1 个令牌
+func (sl *ScheduledLimiter) Allow() bool {
+	return sl.TryConsume(1)
+}
+
+// Rate 返回当前时间段的速率
+func (sl *ScheduledLimiter) Rate() int {
+	sl.update()
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	return sl.rate
+}
+
+// update 切换到当前时间段的速率
+func (sl *ScheduledLimiter) update() {
+	now := sl.tb.Clock().Now()
+	rate := sl.rateAt(now)
+
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	if rate != sl.rate {
+		sl.rate = rate
+		sl.tb.setRate(rate)
+	}
+}
+
+// rateAt 返回 t 所在时间段的速率
+func (sl *ScheduledLimiter) rateAt(t time.Time) int {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	for _, w := range sl.windows {
+		if w.contains(offset) {
+			return w.Rate
+		}
+	}
+	return sl.defaultRate
+}
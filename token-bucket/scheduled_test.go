@@ -0,0 +1,51 @@
+package tokenbucket
+
+import (
+	"testing"
+	"time"
+)
+
+// TestScheduledLimiter 测试跨越时间段边界时速率随之切换
+func TestScheduledLimiter(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 21, 59, 0, 0, time.UTC))
+	tb := NewTokenBucketWithClock(1000, 10, clock)
+	sl := NewScheduledLimiter(tb, 10, []RateWindow{
+		{Start: 22 * time.Hour, End: 6 * time.Hour, Rate: 100}, // 夜间闲时, 跨过零点
+		{Start: 23 * time.Hour, End: 24 * time.Hour, Rate: 50}, // 与上一个重叠, 不生效
+		{Start: 12 * time.Hour, End: 13 * time.Hour, Rate: 20}, // 午间
+	})
+
+	// measure 清空令牌桶后推进 1 秒, 返回这 1 秒内放行的数量
+	measure := func() int {
+		tb.Drain()
+		clock.Advance(time.Second)
+		n := 0
+		for sl.Allow() {
+			n++
+		}
+		return n
+	}
+
+	cases := []struct {
+		at   time.Time
+		rate int
+	}{
+		{time.Date(2024, 1, 1, 21, 58, 0, 0, time.UTC), 10},
+		{time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC), 100},
+		{time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC), 100},
+		{time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC), 100},
+		{time.Date(2024, 1, 2, 6, 0, 0, 0, time.UTC), 10},
+		{time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC), 20},
+		{time.Date(2024, 1, 2, 13, 0, 0, 0, time.UTC), 10},
+	}
+	for _, c := range cases {
+		clock.Advance(c.at.Sub(clock.Now()))
+		sl.Allow()
+		if got := measure(); got != c.rate {
+			t.Errorf("%s 之后 1 秒应放行 %d 个, 实际: %d", c.at.Format("15:04"), c.rate, got)
+		}
+		if got := sl.Rate(); got != c.rate {
+			t.Errorf("%s 速率应为 %d, 实际: %d", c.at.Format("15:04"), c.rate, got)
+		}
+	}
+}
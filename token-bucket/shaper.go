@@ -0,0 +1,32 @@
+package tokenbucket
+
+import "time"
+
+// ShaperLimiter 令牌桶 + 漏桶两级整形器
+// 令牌桶作为突发闸门，限制一段时间内进入的总量，允许最多 capacity 的突发；
+// 漏桶负责平滑输出，通过的请求按漏桶的速率依次流出。
+// 组合效果：突发最多被接纳令牌桶容量那么多，但实际发送始终被整形为漏桶速率，
+// 长期速率不超过两者中较小的一个
+type ShaperLimiter struct {
+	tb *TokenBucket // 突发闸门
+	lb *LeakyBucket // 平滑输出
+}
+
+// NewShaperLimiter 创建一个两级整形器
+func NewShaperLimiter(tb *TokenBucket, lb *LeakyBucket) *ShaperLimiter {
+	return &ShaperLimiter{tb: tb, lb: lb}
+}
+
+// Submit 提交 n 个单位，需要依次通过令牌桶和漏桶
+// 通过时返回 true 和发送前需要等待的时间；漏桶已满时归还令牌并返回 false
+func (sl *ShaperLimiter) Submit(n int) (time.Duration, bool) {
+	if !sl.tb.TryConsume(n) {
+		return 0, false
+	}
+	delay, ok := sl.lb.Submit(n)
+	if !ok {
+		sl.tb.Refund(n)
+		return 0, false
+	}
+	return delay, true
+}
@@ -0,0 +1,40 @@
+package tokenbucket
+
+import (
+	"testing"
+	"time"
+)
+
+// TestShaperLimiter 测试突发受令牌桶容量限制, 输出按漏桶速率排开
+func TestShaperLimiter(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	tb := NewTokenBucketWithClock(10, 1, clock)
+	lb := NewLeakyBucketWithClock(20, 5, clock)
+	sl := NewShaperLimiter(tb, lb)
+
+	// 一次突发: 令牌桶放行 10 个, 漏桶按 5/s 每 200ms 发出一个
+	var delays []time.Duration
+	for i := 0; i < 15; i++ {
+		if delay, ok := sl.Submit(1); ok {
+			delays = append(delays, delay)
+		}
+	}
+	if len(delays) != 10 {
+		t.Fatalf("突发应被令牌桶容量限制为 10 个, 实际: %d", len(delays))
+	}
+	for i, delay := range delays {
+		if want := time.Duration(i+1) * 200 * time.Millisecond; delay != want {
+			t.Errorf("第 %d 个应在 %v 后发出, 实际: %v", i+1, want, delay)
+		}
+	}
+
+	// 漏桶满时归还令牌
+	tb2 := NewTokenBucketWithClock(10, 1, clock)
+	sl2 := NewShaperLimiter(tb2, NewLeakyBucketWithClock(3, 5, clock))
+	for i := 0; i < 5; i++ {
+		sl2.Submit(1)
+	}
+	if tokens := tb2.GetTokens(); tokens != 7 {
+		t.Errorf("漏桶拒绝的请求应归还令牌, 剩余令牌应为 7, 实际: %d", tokens)
+	}
+}
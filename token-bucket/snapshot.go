@@ -0,0 +1,45 @@
+package tokenbucket
+
+import "time"
+
+// BucketState 令牌桶的内存快照，用于蓝绿部署时在进程间交接令牌余量
+type BucketState struct {
+	Capacity    int           // 桶的容量
+	Rate        int           // 令牌生成速率（每秒）
+	Interval    time.Duration // 每个令牌的生成间隔，0 表示按 Rate 补充，见 NewTokenBucketInterval
+	Tokens      int           // 导出时的令牌数
+	SinceRefill time.Duration // 导出时距上次填充已累积、尚未折算成令牌的时间
+}
+
+// Export 导出令牌桶当前状态
+// 时间以相对上次填充的时长保存，而不是绝对时间，不受两台机器时钟差异或
+// 墙上时间跳变的影响
+func (tb *TokenBucket) Export() BucketState {
+	tb.lock()
+	defer tb.unlock()
+
+	tb.refill()
+	return BucketState{
+		Capacity:    tb.capacity,
+		Rate:        tb.rate,
+		Interval:    tb.interval,
+		Tokens:      tb.tokens,
+		SinceRefill: tb.now().Sub(tb.lastRefill),
+	}
+}
+
+// Import 从快照恢复令牌桶状态，使新实例继承旧实例的令牌余量而不是满桶启动
+// 导出与导入之间的交接时间不计入补充，偏保守
+func (tb *TokenBucket) Import(state BucketState) {
+	tb.lock()
+	defer tb.unlock()
+
+	tb.capacity = state.Capacity
+	tb.rate = state.Rate
+	tb.interval = state.Interval
+	tb.tokens = state.Tokens
+	if tb.tokens > tb.capacity {
+		tb.tokens = tb.capacity
+	}
+	tb.lastRefill = tb.now().Add(-state.SinceRefill)
+}
@@ -0,0 +1,56 @@
+package tokenbucket
+
+import "time"
+
+// Stats 令牌桶的长期运行统计
+// 用于核对实际速率是否与配置一致
+type Stats struct {
+	Granted       int64         // refill 累计补充的令牌数（桶满时丢弃的部分不计）
+	Consumed      int64         // 累计消费的令牌数
+	Uptime        time.Duration // 自创建以来经过的时间
+	EffectiveRate float64       // 实际补充速率（每秒），即 Granted / Uptime
+}
+
+// Stats 返回令牌桶的运行统计
+// 桶一直有消费、没有因满而丢弃令牌时，EffectiveRate 应接近 rate。
+// 零值或反序列化得到的令牌桶没有创建时间，Uptime 和 EffectiveRate 为 0
+func (tb *TokenBucket) Stats() Stats {
+	tb.lock()
+	defer tb.unlock()
+
+	tb.refill()
+
+	s := Stats{
+		Granted:  tb.granted,
+		Consumed: tb.consumed,
+	}
+	if !tb.createdAt.IsZero() {
+		s.Uptime = tb.now().Sub(tb.createdAt)
+	}
+	if s.Uptime > 0 {
+		s.EffectiveRate = float64(s.Granted) / s.Uptime.Seconds()
+	}
+	return s
+}
+
+// Extremes 返回令牌桶自创建以来观察到的最少和最多令牌数
+// 用于判断桶是否曾经耗尽或一直是满的：min 始终远高于 0 说明速率配置过高，
+// max 始终达不到容量说明突发容量从未被用到。令牌数在每次加锁的操作前后及补充时记录，
+// 无锁快速路径的消费在下一次加锁时并入；透支或预留时 min 可能为负
+func (tb *TokenBucket) Extremes() (min, max int) {
+	tb.lock()
+	defer tb.unlock()
+
+	tb.refill()
+	return tb.minTokens, tb.maxTokens
+}
+
+// observe 用当前令牌数更新 Extremes 的记录，调用方需持有锁
+func (tb *TokenBucket) observe() {
+	if tb.tokens < tb.minTokens {
+		tb.minTokens = tb.tokens
+	}
+	if tb.tokens > tb.maxTokens {
+		tb.maxTokens = tb.tokens
+	}
+}
@@ -0,0 +1,71 @@
+package tokenbucket
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestStatsEffectiveRate 模拟运行一分钟，验证实际速率与配置一致
+// 每 100ms 只累积 0.7 个令牌，若 refill 丢弃小数部分，实际速率会明显偏低
+func TestStatsEffectiveRate(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	rate := 7
+	tb := NewTokenBucketWithClock(10, rate, clock)
+	tb.Drain()
+
+	for i := 0; i < 600; i++ {
+		clock.Advance(100 * time.Millisecond)
+		for tb.TryConsume(1) {
+		}
+	}
+
+	stats := tb.Stats()
+	t.Logf("统计: %+v", stats)
+
+	if stats.Uptime != time.Minute {
+		t.Errorf("运行时间应为 1 分钟, 实际: %v", stats.Uptime)
+	}
+	if math.Abs(stats.EffectiveRate-float64(rate)) > float64(rate)*0.02 {
+		t.Errorf("实际速率 %.3f 偏离配置速率 %d", stats.EffectiveRate, rate)
+	}
+	if stats.Consumed != stats.Granted {
+		t.Errorf("补充的令牌应被全部消费, 补充: %d, 消费: %d", stats.Granted, stats.Consumed)
+	}
+}
+
+// TestExtremes 测试按脚本消费时记录的最少与最多令牌数与已知轨迹一致
+func TestExtremes(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	tb := NewTokenBucketWithClock(5, 10, clock)
+
+	// 5 -> 4 -> 3, 补充到 5 后消费到 4
+	tb.TryConsume(1)
+	tb.TryConsume(1)
+	if min, max := tb.Extremes(); min != 3 || max != 5 {
+		t.Errorf("期望 (3, 5), 实际: (%d, %d)", min, max)
+	}
+	clock.Advance(200 * time.Millisecond)
+	tb.TryConsume(1)
+	if min, max := tb.Extremes(); min != 3 || max != 5 {
+		t.Errorf("期望 (3, 5), 实际: (%d, %d)", min, max)
+	}
+
+	// 一次消费到 0, 之后补充 1 个
+	tb.TryConsume(4)
+	clock.Advance(100 * time.Millisecond)
+	if min, max := tb.Extremes(); min != 0 || max != 5 {
+		t.Errorf("期望 (0, 5), 实际: (%d, %d)", min, max)
+	}
+	if tokens := tb.GetTokens(); tokens != 1 {
+		t.Errorf("期望剩余 1 个令牌, 实际: %d", tokens)
+	}
+
+	// 由外部注入令牌的桶从空桶开始记录, 从未达到容量
+	b := NewManualRefillBucket(5)
+	b.AddTokens(3)
+	b.TryConsume(1)
+	if min, max := b.tb.Extremes(); min != 0 || max != 3 {
+		t.Errorf("期望 (0, 3), 实际: (%d, %d)", min, max)
+	}
+}
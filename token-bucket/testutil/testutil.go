@@ -0,0 +1,71 @@
+// Package testutil 提供测试令牌桶实际吞吐量的辅助函数
+package testutil
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tokenbucket "example.com/go-examples/algorithm/token-bucket"
+)
+
+// manualSteps 使用手动时钟时将 duration 拆分的步数
+const manualSteps = 1000
+
+// MeasureThroughput 使用 consumers 个并发消费者持续调用 TryConsume(1)，
+// 返回 duration 内实际达到的速率（每秒）
+// 测量前先清空桶，排除初始突发量的影响，只统计按速率补充的令牌。
+// 令牌桶使用 ManualClock 时按步推进模拟时间，不需要真实等待 duration
+func MeasureThroughput(tb *tokenbucket.TokenBucket, duration time.Duration, consumers int) float64 {
+	tb.Drain()
+
+	var granted int64
+	if clock, ok := tb.Clock().(*tokenbucket.ManualClock); ok {
+		step := duration / manualSteps
+		for i := 0; i < manualSteps; i++ {
+			clock.Advance(step)
+			// 每一步让所有消费者抢到桶空为止
+			hammer(tb, consumers, &granted, func() bool { return false })
+		}
+	} else {
+		deadline := time.Now().Add(duration)
+		hammer(tb, consumers, &granted, func() bool { return time.Now().Before(deadline) })
+	}
+
+	return float64(granted) / duration.Seconds()
+}
+
+// hammer 启动 consumers 个消费者调用 TryConsume(1)，
+// 每个消费者在令牌耗尽且 keepGoing 返回 false 时退出
+func hammer(tb *tokenbucket.TokenBucket, consumers int, granted *int64, keepGoing func() bool) {
+	var wg sync.WaitGroup
+	for i := 0; i < consumers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if tb.TryConsume(1) {
+					atomic.AddInt64(granted, 1)
+					continue
+				}
+				if !keepGoing() {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// AssertThroughput 测量实际吞吐量，并检查与配置速率的相对误差不超过 tolerance
+func AssertThroughput(t testing.TB, tb *tokenbucket.TokenBucket, duration time.Duration, consumers int, tolerance float64) {
+	t.Helper()
+
+	rate := float64(tb.Rate())
+	got := MeasureThroughput(tb, duration, consumers)
+	if math.Abs(got-rate)/rate > tolerance {
+		t.Errorf("实际吞吐量 %.2f/s 与配置速率 %.0f/s 的误差超过 %.0f%%", got, rate, tolerance*100)
+	}
+}
@@ -0,0 +1,25 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	tokenbucket "example.com/go-examples/algorithm/token-bucket"
+)
+
+// TestMeasureThroughputManualClock 使用手动时钟测量吞吐量
+func TestMeasureThroughputManualClock(t *testing.T) {
+	clock := tokenbucket.NewManualClock(time.Now())
+	tb := tokenbucket.NewTokenBucketWithClock(100, 50, clock)
+
+	start := time.Now()
+	got := MeasureThroughput(tb, time.Minute, 4)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("手动时钟下不应真实等待一分钟, 实际耗时: %v", elapsed)
+	}
+	if got < 45 || got > 55 {
+		t.Errorf("吞吐量应接近 50/s, 实际: %.2f", got)
+	}
+
+	AssertThroughput(t, tokenbucket.NewTokenBucketWithClock(100, 200, clock), 10*time.Second, 8, 0.1)
+}
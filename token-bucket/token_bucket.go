@@ -1,78 +1,370 @@
 package tokenbucket
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // TokenBucket 令牌桶结构
 type TokenBucket struct {
-	capacity     int       // 桶的容量
-	tokens       int       // 当前令牌数
-	rate         int       // 令牌生成速率（每秒）
-	lastRefill   time.Time // 上次填充时间
-	mu           sync.Mutex
+	capacity    int           // 桶的容量
+	tokens      int           // 当前令牌数
+	rate        int           // 令牌生成速率（每秒）
+	interval    time.Duration // 每个令牌的生成间隔，非 0 时代替 rate 计算补充量
+	lastRefill  time.Time     // 上次填充时间
+	allowed     int64         // 消费成功的次数
+	rejected    int64         // 被限流的次数
+	maxDebt     int           // 最多可透支的令牌数
+	coolingDown bool          // 透支达到上限后的冷却期
+	clock       Clock         // 时间来源
+	createdAt   time.Time     // 创建时间
+	granted     int64         // refill 累计补充的令牌数
+	consumed    int64         // 累计消费的令牌数
+	delays      []int64       // Wait 等待时间的直方图，见 DelayHistogram
+	minTokens   int           // 观察到的最少令牌数，见 Extremes
+	maxTokens   int           // 观察到的最多令牌数，见 Extremes
+	mu          sync.Mutex
+
+	// 无锁快速路径的状态，见 fast_path.go
+	fast      atomic.Int64 // 快速路径可消费的令牌数加 fastBias，0 表示快速路径关闭
+	fastNext  atomic.Int64 // 下一个令牌生成的时刻（UnixNano），到达后需走加锁路径补充
+	fastAllow atomic.Int64 // 快速路径成功消费的次数，尚未并入 allowed
+
+	// OnRefill 在补充令牌后调用，added 为本次补充的数量，total 为补充后的令牌数
+	// OnConsume 在成功消费后调用，n 为消费的数量，remaining 为剩余令牌数
+	// 用于调试时记录令牌流动。回调在持有锁时同步调用，不能阻塞，也不能再调用令牌桶的方法；
+	// 需要在令牌桶开始使用前设置
+	OnRefill  func(added, total int)
+	OnConsume func(n, remaining int)
+
+	// SpinWait 大于 0 时，Wait/WaitN 只用定时器等待到距令牌到达还剩 SpinWait 的时刻，
+	// 剩余时间忙等（循环让出 CPU 并检查时间），见 wait.go。需要在令牌桶开始使用前设置
+	SpinWait time.Duration
 }
 
 // NewTokenBucket 创建一个新的令牌桶
 // capacity: 桶的容量
 // rate: 令牌生成速率（每秒）
 func NewTokenBucket(capacity, rate int) *TokenBucket {
+	return NewTokenBucketWithClock(capacity, rate, realClock{})
+}
+
+// NewTokenBucketWithClock 使用指定的时间来源创建令牌桶
+// 测试中可传入 ManualClock 手动推进时间；TryConsumeContext 的等待仍使用真实计时器
+func NewTokenBucketWithClock(capacity, rate int, clock Clock) *TokenBucket {
+	now := clock.Now()
 	return &TokenBucket{
 		capacity:   capacity,
 		tokens:     capacity, // 初始时桶满
 		rate:       rate,
-		lastRefill: time.Now(),
+		lastRefill: now,
+		clock:      clock,
+		createdAt:  now,
+		minTokens:  capacity,
+		maxTokens:  capacity,
 	}
 }
 
+// NewTokenBucketInterval 按"每 interval 生成一个令牌"创建令牌桶
+// 适合需要亚毫秒精度的高频限流器，如 100µs 一个令牌即每秒 10000 个。
+// 补充量按 elapsed / interval 计算，不经过每秒速率的取整；
+// Rate 等按每秒速率展示的地方返回 time.Second / interval 取整后的值
+func NewTokenBucketInterval(capacity int, interval time.Duration) *TokenBucket {
+	return NewTokenBucketIntervalWithClock(capacity, interval, realClock{})
+}
+
+// NewTokenBucketIntervalWithClock 使用指定的时间来源创建按间隔生成令牌的令牌桶
+// interval 必须在 (0, 1s] 内，否则 panic：与按每秒速率创建的令牌桶一样，速率至少为每秒 1 个
+func NewTokenBucketIntervalWithClock(capacity int, interval time.Duration, clock Clock) *TokenBucket {
+	if interval <= 0 || interval > time.Second {
+		panic(fmt.Sprintf("tokenbucket: interval %v out of range (0, 1s]", interval))
+	}
+	tb := NewTokenBucketWithClock(capacity, int(time.Second/interval), clock)
+	tb.interval = interval
+	return tb
+}
+
+// NewTokenBucketBurst 按持续速率和最大突发量创建令牌桶
+// rate: 持续速率（每秒生成的令牌数）
+// burst: 最多可累积的令牌数，即允许的最大突发请求量
+// 令牌桶的容量即为 burst，与 rate 相互独立：burst 大于 rate 时，
+// 空闲后可以一次放行超过一秒配额的请求，之后仍按 rate 恢复
+func NewTokenBucketBurst(rate, burst int) *TokenBucket {
+	return NewTokenBucket(burst, rate)
+}
+
 // TryConsume 尝试消费令牌
 // count: 需要消费的令牌数
 // 返回: 是否成功消费
+// 无需补充令牌且没有竞争时通过 CAS 消费，不获取锁，见 tryConsumeFast
 func (tb *TokenBucket) TryConsume(count int) bool {
-	tb.mu.Lock()
-	defer tb.mu.Unlock()
+	if tb.tryConsumeFast(count) {
+		return true
+	}
+
+	tb.lock()
+	defer tb.unlock()
 
 	// 重新计算令牌数
 	tb.refill()
 
+	// 本次结束后重新开启快速路径
+	defer tb.arm()
+
 	if tb.tokens >= count {
 		tb.tokens -= count
+		tb.consumed += int64(count)
+		tb.allowed++
+		tb.notifyConsume(count)
 		return true
 	}
+	tb.rejected++
 	return false
 }
 
+// Allow 尝试消费 1 个令牌
+func (tb *TokenBucket) Allow() bool {
+	return tb.TryConsume(1)
+}
+
+// AllowWithReason 尝试消费 n 个令牌，被拒绝时原因为 ReasonInsufficientTokens
+func (tb *TokenBucket) AllowWithReason(n int) (bool, Reason) {
+	if tb.TryConsume(n) {
+		return true, ReasonOK
+	}
+	return false, ReasonInsufficientTokens
+}
+
+// Counts 返回 TryConsume 成功与被限流的次数
+func (tb *TokenBucket) Counts() (allowed, rejected int64) {
+	tb.lock()
+	defer tb.unlock()
+	return tb.allowed, tb.rejected
+}
+
+// TryConsumeContext 尝试消费令牌，令牌不足时等待补充
+// 在令牌到达前 ctx 结束（如超时）则返回 false；count 超过桶容量时直接返回 false。
+// 速率为 0 时不会按时间补充，阻塞等待 ctx 结束，期间按 idleRecheck 间隔重新检查
+func (tb *TokenBucket) TryConsumeContext(ctx context.Context, count int) bool {
+	for {
+		if tb.TryConsume(count) {
+			return true
+		}
+
+		wait, _, ok := tb.retryAfter(count)
+		if !ok {
+			return false
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+			// 令牌可能已被其他调用者抢先消费，重新尝试
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// now 返回令牌桶时间来源的当前时间
+// 零值或反序列化得到的令牌桶没有设置时间来源，使用系统时间
+func (tb *TokenBucket) now() time.Time {
+	if tb.clock == nil {
+		return time.Now()
+	}
+	return tb.clock.Now()
+}
+
 // refill 根据时间间隔补充令牌
 func (tb *TokenBucket) refill() {
-	now := time.Now()
-	elapsed := now.Sub(tb.lastRefill).Seconds()
+	per := tb.tokenInterval()
+	if per <= 0 {
+		return
+	}
+	now := tb.now()
 
 	// 计算应该补充的令牌数
-	newTokens := int(elapsed * float64(tb.rate))
+	newTokens := int(now.Sub(tb.lastRefill) / per)
 
 	if newTokens > 0 {
-		tb.tokens += newTokens
-		if tb.tokens > tb.capacity {
+		if tb.tokens+newTokens >= tb.capacity {
+			// 桶满后多余的令牌丢弃，从现在开始重新累积
+			added := tb.capacity - tb.tokens
+			tb.granted += int64(added)
 			tb.tokens = tb.capacity
+			tb.lastRefill = now
+			tb.observe()
+			if added > 0 && tb.OnRefill != nil {
+				tb.OnRefill(added, tb.tokens)
+			}
+			return
+		}
+
+		// 只推进补充的整数个令牌对应的时间，保留不足一个令牌的部分，
+		// 否则频繁调用时小数部分不断丢失，实际速率会低于 rate
+		tb.tokens += newTokens
+		tb.granted += int64(newTokens)
+		tb.lastRefill = tb.lastRefill.Add(time.Duration(newTokens) * per)
+		tb.observe()
+		if tb.OnRefill != nil {
+			tb.OnRefill(newTokens, tb.tokens)
 		}
-		tb.lastRefill = now
+	}
+}
+
+// notifyConsume 在设置了 OnConsume 时报告一次成功的消费，调用方需持有锁
+func (tb *TokenBucket) notifyConsume(n int) {
+	if tb.OnConsume != nil {
+		tb.OnConsume(n, tb.tokens)
+	}
+}
+
+// tokenInterval 返回生成一个令牌所需的时间，速率为 0 时返回 0
+func (tb *TokenBucket) tokenInterval() time.Duration {
+	if tb.interval > 0 {
+		return tb.interval
+	}
+	if tb.rate <= 0 {
+		return 0
+	}
+	return time.Second / time.Duration(tb.rate)
+}
+
+// Refund 归还令牌
+// 用于下游调用失败时退回已消费的令牌，使失败请求不计入速率
+// 归还后的令牌数不会超过桶的容量；n <= 0 时什么也不做，不能借此扣减令牌
+func (tb *TokenBucket) Refund(n int) {
+	if n <= 0 {
+		return
+	}
+
+	tb.lock()
+	defer tb.unlock()
+
+	// 先补充令牌，保证 lastRefill 与当前令牌数一致
+	tb.refill()
+
+	tb.tokens += n
+	if tb.tokens > tb.capacity {
+		tb.tokens = tb.capacity
+	}
+}
+
+// Drain 清空桶内令牌
+// 用于检测到滥用后强制冷却，之后只能等待按速率重新补充
+func (tb *TokenBucket) Drain() {
+	tb.lock()
+	defer tb.unlock()
+
+	// 先结算已累积的时间，避免清空后被补发之前的令牌
+	tb.refill()
+	tb.tokens = 0
+	tb.lastRefill = tb.now()
+}
+
+// TimeUntilAvailable 返回距离可以消费 count 个令牌还需等待的时间
+// 令牌充足时返回 0；count 超过桶容量，或速率为 0 且令牌不足时，不会随时间满足，返回 -1
+func (tb *TokenBucket) TimeUntilAvailable(count int) time.Duration {
+	tb.lock()
+	defer tb.unlock()
+
+	if count > tb.capacity {
+		return -1
+	}
+
+	tb.refill()
+	if tb.tokens >= count {
+		return 0
+	}
+	if tb.tokenInterval() <= 0 {
+		return -1
+	}
+
+	// 缺少的令牌按速率补充所需时间，扣除自上次填充以来已累积的部分
+	deficit := count - tb.tokens
+	wait := time.Duration(deficit) * tb.tokenInterval()
+	wait -= tb.now().Sub(tb.lastRefill)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// idleRecheck 是速率为 0 时等待者重新检查令牌的间隔
+// 此时令牌只会通过 Refund、调整速率等方式出现，按间隔检查而不是空转
+const idleRecheck = 100 * time.Millisecond
+
+// retryAfter 返回等待者下一次尝试消费 count 个令牌前需要等待的时间
+// idle 表示速率为 0、等待的是 idleRecheck 而不是令牌到达；count 超过桶容量、永远无法满足时 ok 为 false
+func (tb *TokenBucket) retryAfter(count int) (wait time.Duration, idle, ok bool) {
+	if wait := tb.TimeUntilAvailable(count); wait >= 0 {
+		return wait, false, true
+	}
+
+	tb.mu.Lock()
+	capacity := tb.capacity
+	tb.mu.Unlock()
+	if count > capacity {
+		return 0, false, false
+	}
+	return idleRecheck, true, true
+}
+
+// setRate 调整令牌生成速率
+// 调整前先按旧速率结算已累积的令牌
+func (tb *TokenBucket) setRate(rate int) {
+	tb.lock()
+	defer tb.unlock()
+
+	tb.refill()
+	tb.rate = rate
+	tb.interval = 0
+}
+
+// drainUntil 清空令牌桶，并在 until 之前不再补充令牌
+func (tb *TokenBucket) drainUntil(until time.Time) {
+	tb.lock()
+	defer tb.unlock()
+
+	tb.refill()
+	tb.tokens = 0
+	if until.After(tb.lastRefill) {
+		// refill 只在距 lastRefill 满一个令牌间隔后才补充
+		tb.lastRefill = until
 	}
 }
 
 // GetTokens 获取当前令牌数（仅用于测试）
 func (tb *TokenBucket) GetTokens() int {
-	tb.mu.Lock()
-	defer tb.mu.Unlock()
+	tb.lock()
+	defer tb.unlock()
 	tb.refill()
 	return tb.tokens
 }
 
-// Info 获取令牌桶信息
-func (tb *TokenBucket) Info() string {
+// Rate 返回令牌生成速率（每秒）
+func (tb *TokenBucket) Rate() int {
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
+	return tb.rate
+}
+
+// Clock 返回令牌桶使用的时间来源
+func (tb *TokenBucket) Clock() Clock {
+	if tb.clock == nil {
+		return realClock{}
+	}
+	return tb.clock
+}
+
+// Info 获取令牌桶信息
+func (tb *TokenBucket) Info() string {
+	tb.lock()
+	defer tb.unlock()
 	tb.refill()
 	return fmt.Sprintf("Capacity: %d, Rate: %d/s, Tokens: %d", tb.capacity, tb.rate, tb.tokens)
 }
@@ -0,0 +1,46 @@
+package tokenbucket
+
+// NewTokenBucketDebt 创建一个允许透支的令牌桶
+// capacity: 桶的容量
+// rate: 令牌生成速率（每秒）
+// maxDebt: 最多可透支的令牌数，令牌数最低可降到 -maxDebt
+func NewTokenBucketDebt(capacity, rate, maxDebt int) *TokenBucket {
+	tb := NewTokenBucket(capacity, rate)
+	tb.maxDebt = maxDebt
+	return tb
+}
+
+// TryConsumeWithDebt 尝试消费令牌，令牌不足时允许向未来透支
+// 透支后令牌数不能低于 -maxDebt；一旦透支达到上限或因超出上限被拒绝，
+// 进入冷却期，之后的请求都被拒绝，直到令牌补充回正数
+func (tb *TokenBucket) TryConsumeWithDebt(count int) bool {
+	tb.lock()
+	defer tb.unlock()
+
+	tb.refill()
+
+	if tb.coolingDown {
+		if tb.tokens <= 0 {
+			tb.rejected++
+			return false
+		}
+		tb.coolingDown = false
+	}
+
+	if tb.tokens-count < -tb.maxDebt {
+		if tb.tokens < 0 {
+			tb.coolingDown = true
+		}
+		tb.rejected++
+		return false
+	}
+
+	tb.tokens -= count
+	tb.consumed += int64(count)
+	tb.allowed++
+	tb.notifyConsume(count)
+	if tb.maxDebt > 0 && tb.tokens <= -tb.maxDebt {
+		tb.coolingDown = true
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package tokenbucket
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTryConsumeWithDebt 测试透支与透支上限后的冷却
+func TestTryConsumeWithDebt(t *testing.T) {
+	tb := NewTokenBucketDebt(5, 100, 3)
+
+	if !tb.TryConsumeWithDebt(5) {
+		t.Fatal("令牌充足时应该成功")
+	}
+
+	// 令牌不足时透支
+	if !tb.TryConsumeWithDebt(2) {
+		t.Fatal("透支额度内应该成功")
+	}
+	if got := tb.GetTokens(); got > -1 {
+		t.Errorf("透支后令牌数应为负数, 实际: %d", got)
+	}
+
+	// 超出透支上限被拒绝并进入冷却
+	if tb.TryConsumeWithDebt(2) {
+		t.Fatal("超出透支上限应该被拒绝")
+	}
+	if tb.TryConsumeWithDebt(1) {
+		t.Error("冷却期内即使在透支额度内也应该被拒绝")
+	}
+	if tb.TryConsume(1) {
+		t.Error("透支期间普通消费也应该被拒绝")
+	}
+
+	// 令牌补充回正数后恢复
+	time.Sleep(50 * time.Millisecond)
+	if !tb.TryConsumeWithDebt(1) {
+		t.Error("令牌补充回正数后应该成功")
+	}
+}
+
+// TestDebtLimitReached 测试透支恰好达到上限后进入冷却
+func TestDebtLimitReached(t *testing.T) {
+	tb := NewTokenBucketDebt(2, 1, 2)
+
+	if !tb.TryConsumeWithDebt(4) {
+		t.Fatal("透支到上限应该成功")
+	}
+	if tb.TryConsumeWithDebt(1) {
+		t.Error("透支达到上限后应该进入冷却")
+	}
+}
@@ -2,71 +2,142 @@ package tokenbucket
 
 import (
 	"fmt"
+	"math"
 	"sync"
 	"time"
 )
 
-// TokenBucketDistributed 分布式令牌桶结构（基于Redis）
+// distributedMaxRetries TryConsumeDistributed 遇到写冲突时的最大重试次数
+const distributedMaxRetries = 100
+
+// SharedState 共享存储中保存的令牌桶状态
+type SharedState struct {
+	Tokens float64   // 当前令牌数，保留小数部分
+	Last   time.Time // 上次补充令牌的时间
+}
+
+// SharedStore 令牌桶状态的共享存储，可以用 Redis、etcd 或内存实现
+// 多个进程中的令牌桶通过同一个 key 共享状态，从而实现全局限流
+type SharedStore interface {
+	// GetState 读取 key 的状态，不存在时 ok 为 false
+	GetState(key string) (tokens float64, last time.Time, ok bool)
+	// SetState 比较并写入：仅当 key 的当前状态仍等于 expected 时写入 next 并返回 true
+	// expected 为 nil 表示要求 key 尚不存在
+	SetState(key string, expected *SharedState, next SharedState) bool
+}
+
+// MemoryStore 基于内存的 SharedStore，用于单进程内多个令牌桶共享状态或测试
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]SharedState
+}
+
+// NewMemoryStore 创建一个内存共享存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[string]SharedState)}
+}
+
+// GetState 读取 key 的状态
+func (s *MemoryStore) GetState(key string) (float64, time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.states[key]
+	return st.Tokens, st.Last, ok
+}
+
+// SetState 比较并写入 key 的状态
+func (s *MemoryStore) SetState(key string, expected *SharedState, next SharedState) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, ok := s.states[key]
+	if expected == nil {
+		if ok {
+			return false
+		}
+	} else if !ok || cur.Tokens != expected.Tokens || !cur.Last.Equal(expected.Last) {
+		return false
+	}
+	s.states[key] = next
+	return true
+}
+
+// TokenBucketDistributed 分布式令牌桶结构
+// 状态保存在 SharedStore 中，每次消费按 读取-计算-比较并写入 的方式更新，冲突时重试。
+// 各实例使用自己的时钟计算补充的令牌，时钟偏差较大时限流精度会下降
 type TokenBucketDistributed struct {
-	capacity     int       // 桶的容量
-	rate         int       // 令牌生成速率（每秒）
-	lastRefill   time.Time // 上次填充时间
-	tokens       int       // 当前令牌数
-	mu           sync.Mutex
+	store    SharedStore // 共享存储
+	key      string      // 令牌桶在共享存储中的 key
+	capacity int         // 桶的容量
+	rate     int         // 令牌生成速率（每秒）
+	clock    Clock       // 时间来源
 }
 
 // NewTokenBucketDistributed 创建一个新的分布式令牌桶
+// 状态保存在独享的内存存储中，需要跨实例共享时使用 NewTokenBucketDistributedWithStore
 func NewTokenBucketDistributed(capacity, rate int) *TokenBucketDistributed {
+	return NewTokenBucketDistributedWithStore(NewMemoryStore(), "default", capacity, rate)
+}
+
+// NewTokenBucketDistributedWithStore 创建一个状态保存在 store 中 key 下的分布式令牌桶
+// 使用同一个 store 和 key 的令牌桶共享同一份令牌
+func NewTokenBucketDistributedWithStore(store SharedStore, key string, capacity, rate int) *TokenBucketDistributed {
+	return NewTokenBucketDistributedWithClock(store, key, capacity, rate, realClock{})
+}
+
+// NewTokenBucketDistributedWithClock 使用指定的时间来源创建分布式令牌桶
+func NewTokenBucketDistributedWithClock(store SharedStore, key string, capacity, rate int, clock Clock) *TokenBucketDistributed {
 	return &TokenBucketDistributed{
-		capacity:   capacity,
-		tokens:     capacity,
-		rate:       rate,
-		lastRefill: time.Now(),
+		store:    store,
+		key:      key,
+		capacity: capacity,
+		rate:     rate,
+		clock:    clock,
 	}
 }
 
 // TryConsumeDistributed 尝试消费令牌（分布式场景）
+// 写入冲突时重新读取状态并重试，重试 distributedMaxRetries 次仍冲突时返回 false
 func (tbd *TokenBucketDistributed) TryConsumeDistributed(count int) bool {
-	tbd.mu.Lock()
-	defer tbd.mu.Unlock()
-
-	tbd.refill()
-
-	if tbd.tokens >= count {
-		tbd.tokens -= count
-		return true
+	for i := 0; i < distributedMaxRetries; i++ {
+		expected, next := tbd.load()
+		if next.Tokens < float64(count) {
+			return false
+		}
+		next.Tokens -= float64(count)
+		if tbd.store.SetState(tbd.key, expected, next) {
+			return true
+		}
 	}
 	return false
 }
 
-// refill 补充令牌
-func (tbd *TokenBucketDistributed) refill() {
-	now := time.Now()
-	elapsed := now.Sub(tbd.lastRefill).Seconds()
-
-	newTokens := int(elapsed * float64(tbd.rate))
+// load 读取共享状态并计算补充令牌后的状态
+// 返回读取到的原状态（不存在时为 nil）和补充后的状态
+func (tbd *TokenBucketDistributed) load() (*SharedState, SharedState) {
+	now := tbd.clock.Now()
+	tokens, last, ok := tbd.store.GetState(tbd.key)
+	if !ok {
+		return nil, SharedState{Tokens: float64(tbd.capacity), Last: now}
+	}
 
-	if newTokens > 0 {
-		tbd.tokens += newTokens
-		if tbd.tokens > tbd.capacity {
-			tbd.tokens = tbd.capacity
-		}
-		tbd.lastRefill = now
+	expected := &SharedState{Tokens: tokens, Last: last}
+	elapsed := now.Sub(last).Seconds()
+	if elapsed <= 0 {
+		// 其他实例的时钟较快时不补充，也不回退 Last
+		return expected, *expected
 	}
+	tokens = math.Min(float64(tbd.capacity), tokens+elapsed*float64(tbd.rate))
+	return expected, SharedState{Tokens: tokens, Last: now}
 }
 
 // GetTokensDistributed 获取当前令牌数
 func (tbd *TokenBucketDistributed) GetTokensDistributed() int {
-	tbd.mu.Lock()
-	defer tbd.mu.Unlock()
-	tbd.refill()
-	return tbd.tokens
+	_, st := tbd.load()
+	return int(st.Tokens)
 }
 
 // InfoDistributed 获取令牌桶信息
 func (tbd *TokenBucketDistributed) InfoDistributed() string {
-	tbd.mu.Lock()
-	defer tbd.mu.Unlock()
-	tbd.refill()
-	return fmt.Sprintf("Capacity: %d, Rate: %d/s, Tokens: %d", tbd.capacity, tbd.rate, tbd.tokens)
+	return fmt.Sprintf("Capacity: %d, Rate: %d/s, Tokens: %d", tbd.capacity, tbd.rate, tbd.GetTokensDistributed())
 }
@@ -0,0 +1,87 @@
+package tokenbucket
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestDistributedGlobalLimit 测试共享同一存储的两个实例合计不超过全局限额
+func TestDistributedGlobalLimit(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	store := NewMemoryStore()
+	a := NewTokenBucketDistributedWithClock(store, "api", 10, 10, clock)
+	b := NewTokenBucketDistributedWithClock(store, "api", 10, 10, clock)
+
+	consume := func() int64 {
+		var allowed atomic.Int64
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(tb *TokenBucketDistributed) {
+				defer wg.Done()
+				for j := 0; j < 5; j++ {
+					if tb.TryConsumeDistributed(1) {
+						allowed.Add(1)
+					}
+				}
+			}([]*TokenBucketDistributed{a, b}[i%2])
+		}
+		wg.Wait()
+		return allowed.Load()
+	}
+
+	if n := consume(); n != 10 {
+		t.Errorf("两个实例合计应放行 10 个, 实际: %d", n)
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if tokens := b.GetTokensDistributed(); tokens != 5 {
+		t.Errorf("0.5 秒后应补充 5 个令牌, 实际: %d", tokens)
+	}
+	if n := consume(); n != 5 {
+		t.Errorf("补充后两个实例合计应放行 5 个, 实际: %d", n)
+	}
+}
+
+// TestDistributedSeparateKeys 测试不同 key 的令牌桶互不影响
+func TestDistributedSeparateKeys(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	store := NewMemoryStore()
+	a := NewTokenBucketDistributedWithClock(store, "a", 2, 1, clock)
+	b := NewTokenBucketDistributedWithClock(store, "b", 2, 1, clock)
+
+	if !a.TryConsumeDistributed(2) {
+		t.Fatal("a 应能消费 2 个令牌")
+	}
+	if a.TryConsumeDistributed(1) {
+		t.Error("a 的令牌已耗尽")
+	}
+	if !b.TryConsumeDistributed(2) {
+		t.Error("b 的令牌不应受 a 影响")
+	}
+}
+
+// TestMemoryStoreCAS 测试比较并写入的语义
+func TestMemoryStoreCAS(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	first := SharedState{Tokens: 5, Last: now}
+
+	if !store.SetState("k", nil, first) {
+		t.Fatal("key 不存在时应写入成功")
+	}
+	if store.SetState("k", nil, first) {
+		t.Error("key 已存在时期望不存在应写入失败")
+	}
+	if store.SetState("k", &SharedState{Tokens: 4, Last: now}, SharedState{Tokens: 3, Last: now}) {
+		t.Error("状态不匹配时应写入失败")
+	}
+	if !store.SetState("k", &first, SharedState{Tokens: 3, Last: now}) {
+		t.Error("状态匹配时应写入成功")
+	}
+	if tokens, _, ok := store.GetState("k"); !ok || tokens != 3 {
+		t.Errorf("令牌数应为 3, 实际: %.1f", tokens)
+	}
+}
@@ -0,0 +1,58 @@
+package tokenbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// tokenBucketState 令牌桶持久化的 JSON 结构
+type tokenBucketState struct {
+	Capacity   int           `json:"capacity"`
+	Rate       int           `json:"rate"`
+	Interval   time.Duration `json:"interval,omitempty"` // 纳秒，0 表示按 rate 补充
+	Tokens     int           `json:"tokens"`
+	LastRefill time.Time     `json:"lastRefill"`
+}
+
+// MarshalJSON 将令牌桶状态编码为 JSON，用于重启后恢复配额
+func (tb *TokenBucket) MarshalJSON() ([]byte, error) {
+	tb.lock()
+	defer tb.unlock()
+
+	return json.Marshal(tokenBucketState{
+		Capacity:   tb.capacity,
+		Rate:       tb.rate,
+		Interval:   tb.interval,
+		Tokens:     tb.tokens,
+		LastRefill: tb.lastRefill,
+	})
+}
+
+// UnmarshalJSON 从 JSON 恢复令牌桶状态
+// 以保存时的 lastRefill 为起点计算停机期间累积的令牌，补充后不超过容量。
+// 令牌数超过容量时截断为容量；lastRefill 晚于当前时间（保存与加载的机器时钟不一致）时按当前时间处理，
+// 否则补充会停滞到该时间为止
+func (tb *TokenBucket) UnmarshalJSON(data []byte) error {
+	var state tokenBucketState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Capacity <= 0 || state.Rate <= 0 || state.Interval < 0 || state.Interval > time.Second {
+		return fmt.Errorf("invalid token bucket state: capacity=%d, rate=%d, interval=%v", state.Capacity, state.Rate, state.Interval)
+	}
+
+	tb.lock()
+	defer tb.unlock()
+
+	tb.capacity = state.Capacity
+	tb.rate = state.Rate
+	tb.interval = state.Interval
+	tb.tokens = min(state.Tokens, state.Capacity)
+	tb.lastRefill = state.LastRefill
+	if now := tb.now(); tb.lastRefill.After(now) {
+		tb.lastRefill = now
+	}
+	tb.refill()
+	return nil
+}
@@ -1,114 +1,359 @@
-package main
+package tokenbucket
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"testing"
 	"time"
 )
 
-// TokenBucket 令牌桶结构
-type TokenBucket struct {
-	capacity     int       // 桶的容量
-	tokens       int       // 当前令牌数
-	rate         int       // 令牌生成速率（每秒）
-	lastRefill   time.Time // 上次填充时间
+// TestTryConsume 测试基本的令牌消费
+func TestTryConsume(t *testing.T) {
+	tb := NewTokenBucket(5, 1)
+
+	for i := 0; i < 5; i++ {
+		if !tb.TryConsume(1) {
+			t.Fatalf("第 %d 次消费应该成功", i+1)
+		}
+	}
+
+	if tb.TryConsume(1) {
+		t.Error("令牌耗尽后消费应该失败")
+	}
 }
 
-// NewTokenBucket 创建一个新的令牌桶
-func NewTokenBucket(capacity, rate int) *TokenBucket {
-	return &TokenBucket{
-		capacity:   capacity,
-		tokens:     capacity,
-		rate:       rate,
-		lastRefill: time.Now(),
+// TestRefund 测试归还令牌
+func TestRefund(t *testing.T) {
+	tb := NewTokenBucket(10, 1)
+
+	before := tb.GetTokens()
+	if !tb.TryConsume(4) {
+		t.Fatal("消费 4 个令牌应该成功")
+	}
+	tb.Refund(4)
+
+	if got := tb.GetTokens(); got != before {
+		t.Errorf("归还后令牌数应该恢复为 %d, 实际: %d", before, got)
+	}
+
+	// 归还不能超过容量
+	tb.Refund(100)
+	if got := tb.GetTokens(); got != 10 {
+		t.Errorf("归还后令牌数不应超过容量 10, 实际: %d", got)
+	}
+
+	// 归还 0 或负数不改变令牌数
+	tb.Refund(0)
+	tb.Refund(-5)
+	if got := tb.GetTokens(); got != 10 {
+		t.Errorf("归还非正数后令牌数应保持 10, 实际: %d", got)
 	}
 }
 
-// TryConsume 尝试消费令牌
-func (tb *TokenBucket) TryConsume(count int) bool {
-	tb.refill()
+// TestDrain 测试清空令牌
+func TestDrain(t *testing.T) {
+	tb := NewTokenBucket(10, 100)
 
-	if tb.tokens >= count {
-		tb.tokens -= count
-		return true
+	tb.Drain()
+	if tb.TryConsume(1) {
+		t.Error("清空后消费应该失败")
+	}
+
+	// 等待补充令牌
+	time.Sleep(50 * time.Millisecond)
+	if !tb.TryConsume(1) {
+		t.Error("补充令牌后消费应该成功")
 	}
-	return false
 }
 
-// refill 补充令牌
-func (tb *TokenBucket) refill() {
-	now := time.Now()
-	elapsed := now.Sub(tb.lastRefill).Seconds()
+// TestTimeUntilAvailable 测试等待时间计算
+func TestTimeUntilAvailable(t *testing.T) {
+	tb := NewTokenBucket(2, 10)
+
+	if wait := tb.TimeUntilAvailable(1); wait != 0 {
+		t.Errorf("令牌充足时等待时间应为 0, 实际: %v", wait)
+	}
+	if wait := tb.TimeUntilAvailable(3); wait != -1 {
+		t.Errorf("超过容量时应返回 -1, 实际: %v", wait)
+	}
+
+	tb.TryConsume(2)
+	wait := tb.TimeUntilAvailable(1)
+	if wait <= 0 || wait > 100*time.Millisecond {
+		t.Errorf("等待时间应在 (0, 100ms] 之间, 实际: %v", wait)
+	}
+}
 
-	newTokens := int(elapsed * float64(tb.rate))
+// TestBurst 测试突发量大于持续速率
+func TestBurst(t *testing.T) {
+	tb := NewTokenBucketBurst(100, 20)
 
-	if newTokens > 0 {
-		tb.tokens += newTokens
-		if tb.tokens > tb.capacity {
-			tb.tokens = tb.capacity
+	// 空闲时允许一次性突发 20 个请求，超过每秒配额的 1/5 窗口
+	passed := 0
+	for i := 0; i < 30; i++ {
+		if tb.TryConsume(1) {
+			passed++
 		}
-		tb.lastRefill = now
+	}
+	if passed != 20 {
+		t.Errorf("突发请求应该通过 20 个, 实际: %d", passed)
+	}
+
+	// 之后按持续速率恢复，50ms 约补充 5 个
+	time.Sleep(50 * time.Millisecond)
+	if got := tb.GetTokens(); got < 4 || got > 7 {
+		t.Errorf("50ms 后应补充约 5 个令牌, 实际: %d", got)
+	}
+
+	// 长时间空闲后令牌数不超过突发量
+	time.Sleep(300 * time.Millisecond)
+	if got := tb.GetTokens(); got != 20 {
+		t.Errorf("令牌数不应超过突发量 20, 实际: %d", got)
 	}
 }
 
-// GetTokens 获取当前令牌数
-func (tb *TokenBucket) GetTokens() int {
-	tb.refill()
-	return tb.tokens
+// TestJSONRoundTrip 测试 JSON 持久化后按停机时间补充令牌
+func TestJSONRoundTrip(t *testing.T) {
+	tb := NewTokenBucket(10, 100)
+	tb.TryConsume(10)
+
+	data, err := json.Marshal(tb)
+	if err != nil {
+		t.Fatalf("序列化失败: %v", err)
+	}
+
+	// 模拟停机 50ms，期间应累积约 5 个令牌
+	time.Sleep(50 * time.Millisecond)
+
+	var restored TokenBucket
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("反序列化失败: %v", err)
+	}
+	if got := restored.GetTokens(); got < 4 || got > 7 {
+		t.Errorf("恢复后应补充约 5 个令牌, 实际: %d", got)
+	}
+
+	// 停机时间较长时不超过容量
+	time.Sleep(150 * time.Millisecond)
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("反序列化失败: %v", err)
+	}
+	if got := restored.GetTokens(); got != 10 {
+		t.Errorf("恢复后令牌数不应超过容量 10, 实际: %d", got)
+	}
 }
 
-func main() {
-	// 创建一个容量为10，速率为5的令牌桶
-	tb := NewTokenBucket(10, 5)
-
-	fmt.Println("=== 令牌桶算法演示 ===")
-	fmt.Println(tb.Info())
-
-	// 模拟请求
-	fmt.Println("\n=== 模拟请求 ===")
-	requests := []struct {
-		name   string
-		count  int
-		delay  time.Duration
-	}{
-		{"请求1", 3, 0},
-		{"请求2", 5, 100 * time.Millisecond},
-		{"请求3", 4, 0},
-		{"请求4", 2, 200 * time.Millisecond},
-		{"请求5", 6, 0},
-	}
-
-	for _, req := range requests {
-		if req.delay > 0 {
-			time.Sleep(req.delay)
-		}
-		success := tb.TryConsume(req.count)
-		status := "❌ 被限流"
-		if success {
-			status = "✓ 通过"
-		}
-		fmt.Printf("%s: 消费%d个令牌 - %s (剩余: %d)\n",
-			req.name, req.count, status, tb.GetTokens())
-	}
-
-	// 等待令牌补充
-	fmt.Println("\n=== 等待令牌补充 ===")
-	time.Sleep(1 * time.Second)
-	fmt.Printf("等待1秒后: %s\n", tb.Info())
-
-	// 并发测试
-	fmt.Println("\n=== 并发测试 ===")
-	tb2 := NewTokenBucket(5, 2)
-	successCount := 0
-	for i := 0; i < 10; i++ {
-		if tb2.TryConsume(1) {
-			successCount++
+// TestJSONClamp 测试反序列化时令牌数截断为容量、未来的 lastRefill 按当前时间处理
+func TestJSONClamp(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	tb := NewTokenBucketWithClock(10, 1, clock)
+
+	over := fmt.Sprintf(`{"capacity":10,"rate":1,"tokens":50,"lastRefill":%q}`, clock.Now().Format(time.RFC3339Nano))
+	if err := json.Unmarshal([]byte(over), tb); err != nil {
+		t.Fatalf("反序列化失败: %v", err)
+	}
+	if got := tb.GetTokens(); got != 10 {
+		t.Errorf("令牌数应截断为容量 10, 实际: %d", got)
+	}
+
+	future := fmt.Sprintf(`{"capacity":10,"rate":1,"tokens":0,"lastRefill":%q}`, clock.Now().Add(time.Hour).Format(time.RFC3339Nano))
+	if err := json.Unmarshal([]byte(future), tb); err != nil {
+		t.Fatalf("反序列化失败: %v", err)
+	}
+	clock.Advance(time.Second)
+	if got := tb.GetTokens(); got != 1 {
+		t.Errorf("lastRefill 在未来时应从当前时间开始补充, 推进 1 秒后令牌数应为 1, 实际: %d", got)
+	}
+}
+
+// TestTryConsumeContext 测试等待令牌直到截止时间
+func TestTryConsumeContext(t *testing.T) {
+	tb := NewTokenBucket(5, 10)
+	tb.TryConsume(5)
+
+	// 补充 5 个令牌需要 500ms，截止时间过短
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if tb.TryConsumeContext(ctx, 5) {
+		t.Error("截止时间前令牌不足，应该返回 false")
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("应该在截止时间到达时返回, 实际耗时: %v", elapsed)
+	}
+
+	// 截止时间充足，等待令牌到达
+	ctx2, cancel2 := context.WithTimeout(context.Background(), time.Second)
+	defer cancel2()
+	if !tb.TryConsumeContext(ctx2, 1) {
+		t.Error("截止时间内令牌到达，应该返回 true")
+	}
+
+	if tb.TryConsumeContext(ctx2, 6) {
+		t.Error("超过容量时应该返回 false")
+	}
+}
+
+// TestManualClock 测试使用手动时钟确定性地补充令牌
+func TestManualClock(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	tb := NewTokenBucketWithClock(10, 5, clock)
+
+	tb.TryConsume(10)
+	if got := tb.GetTokens(); got != 0 {
+		t.Fatalf("消费后令牌数应为 0, 实际: %d", got)
+	}
+
+	clock.Advance(time.Second)
+	if got := tb.GetTokens(); got != 5 {
+		t.Errorf("推进 1 秒后应补充 5 个令牌, 实际: %d", got)
+	}
+
+	clock.Advance(10 * time.Second)
+	if got := tb.GetTokens(); got != 10 {
+		t.Errorf("令牌数不应超过容量 10, 实际: %d", got)
+	}
+}
+
+// TestExportImport 测试快照导入后保留令牌余量
+func TestExportImport(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	old := NewTokenBucketWithClock(10, 5, clock)
+	old.TryConsume(7)
+
+	state := old.Export()
+
+	// 新实例满桶启动，导入后继承旧实例的令牌余量
+	fresh := NewTokenBucketWithClock(10, 5, clock)
+	fresh.Import(state)
+	if got := fresh.GetTokens(); got != 3 {
+		t.Errorf("导入后令牌数应为 3, 实际: %d", got)
+	}
+
+	// 导入后按速率继续补充
+	clock.Advance(time.Second)
+	if got := fresh.GetTokens(); got != 8 {
+		t.Errorf("推进 1 秒后令牌数应为 8, 实际: %d", got)
+	}
+}
+
+// TestTokenBucketInterval 测试 100µs 间隔下的实际速率
+func TestTokenBucketInterval(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	tb := NewTokenBucketIntervalWithClock(100, 100*time.Microsecond, clock)
+	tb.Drain()
+
+	if got := tb.Rate(); got != 10000 {
+		t.Errorf("每秒速率应为 10000, 实际: %d", got)
+	}
+
+	// 每次推进 30µs, 不足一个间隔的部分必须保留
+	consumed := 0
+	for i := 0; i < 100000; i++ {
+		clock.Advance(30 * time.Microsecond)
+		for tb.TryConsume(1) {
+			consumed++
 		}
 	}
-	fmt.Printf("10个并发请求，通过: %d，被限流: %d\n", successCount, 10-successCount)
+	// 共 3 秒, 应生成 30000 个令牌
+	if consumed != 30000 {
+		t.Errorf("3 秒内应消费 30000 个令牌, 实际: %d", consumed)
+	}
+
+	if wait := tb.TimeUntilAvailable(5); wait != 500*time.Microsecond {
+		t.Errorf("等待 5 个令牌应需 500µs, 实际: %v", wait)
+	}
 }
 
-// Info 获取令牌桶信息
-func (tb *TokenBucket) Info() string {
-	tb.refill()
-	return fmt.Sprintf("容量: %d, 速率: %d/s, 当前令牌: %d", tb.capacity, tb.rate, tb.tokens)
+// TestTokenBucketIntervalInvalid 测试间隔不在 (0, 1s] 内时 panic
+func TestTokenBucketIntervalInvalid(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Millisecond, time.Second + 1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("interval=%v: 期望 panic", interval)
+				}
+			}()
+			NewTokenBucketInterval(1, interval)
+		}()
+	}
+
+	// 1 秒的间隔即每秒 1 个令牌
+	if got := NewTokenBucketInterval(1, time.Second).Rate(); got != 1 {
+		t.Errorf("每秒速率应为 1, 实际: %d", got)
+	}
+}
+
+// TestIntervalStateRoundTrip 测试快照与 JSON 保留令牌间隔，并在导入按秒速率的状态时清除旧间隔
+func TestIntervalStateRoundTrip(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	old := NewTokenBucketIntervalWithClock(100, 100*time.Microsecond, clock)
+	old.Drain()
+
+	fresh := NewTokenBucketWithClock(100, 5, clock)
+	fresh.Import(old.Export())
+	clock.Advance(300 * time.Microsecond)
+	if got := fresh.GetTokens(); got != 3 {
+		t.Errorf("导入后推进 300µs 应补充 3 个令牌, 实际: %d", got)
+	}
+
+	// 导入按秒速率的状态后不再沿用旧间隔
+	fresh.Import(NewTokenBucketWithClock(100, 5, clock).Export())
+	if got := fresh.Export().Interval; got != 0 {
+		t.Errorf("导入按秒速率的状态后间隔应为 0, 实际: %v", got)
+	}
+
+	data, err := json.Marshal(old)
+	if err != nil {
+		t.Fatalf("序列化失败: %v", err)
+	}
+	restored := NewTokenBucket(100, 5)
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("反序列化失败: %v", err)
+	}
+	if got := restored.Export().Interval; got != 100*time.Microsecond {
+		t.Errorf("反序列化后间隔应为 100µs, 实际: %v", got)
+	}
+
+	data, _ = json.Marshal(NewTokenBucket(100, 5))
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("反序列化失败: %v", err)
+	}
+	if got := restored.Export().Interval; got != 0 {
+		t.Errorf("反序列化按秒速率的状态后间隔应为 0, 实际: %v", got)
+	}
+}
+
+// TestCallbacks 测试补充与消费回调按脚本化的顺序触发
+func TestCallbacks(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	tb := NewTokenBucketWithClock(5, 2, clock)
+
+	var events []string
+	tb.OnRefill = func(added, total int) {
+		events = append(events, fmt.Sprintf("refill +%d =%d", added, total))
+	}
+	tb.OnConsume = func(n, remaining int) {
+		events = append(events, fmt.Sprintf("consume -%d =%d", n, remaining))
+	}
+
+	tb.TryConsume(4)
+	tb.TryConsume(2) // 令牌不足, 不触发回调
+	clock.Advance(time.Second)
+	tb.TryConsume(3)
+	clock.Advance(10 * time.Second)
+	tb.GetTokens()
+	tb.GetTokens() // 桶已满, 不触发补充回调
+
+	want := []string{
+		"consume -4 =1",
+		"refill +2 =3",
+		"consume -3 =0",
+		"refill +5 =5",
+	}
+	if strings.Join(events, "; ") != strings.Join(want, "; ") {
+		t.Errorf("回调顺序不符合预期:\n实际: %v\n期望: %v", events, want)
+	}
 }
@@ -0,0 +1,195 @@
+package tokenbucket
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"time"
+)
+
+// ErrExceedsCapacity 表示请求的令牌数超过桶容量，永远无法满足
+var ErrExceedsCapacity = errors.New("tokenbucket: count exceeds capacity")
+
+// DelayBounds 是 DelayHistogram 各区间的上界
+var DelayBounds = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// DelayHistogram Wait/WaitN 调用方阻塞时间的分布
+// Counts[i] 是等待时间不超过 Bounds[i]（且超过 Bounds[i-1]）的次数，
+// 最后一个 Counts 统计超过全部上界的次数，len(Counts) == len(Bounds)+1
+type DelayHistogram struct {
+	Bounds []time.Duration
+	Counts []int64
+}
+
+// Wait 等待并消费 1 个令牌
+func (tb *TokenBucket) Wait(ctx context.Context) error {
+	return tb.WaitN(ctx, 1)
+}
+
+// WaitN 等待并消费 n 个令牌
+// ctx 在令牌到达前结束时返回 ctx.Err()；n 超过桶容量时返回 ErrExceedsCapacity。
+// 等待使用令牌桶时间来源的定时器（如 ManualClock），阻塞时间记入 DelayHistogram；
+// 设置了 SpinWait 时最后一段时间忙等，见 sleep。
+// 速率为 0 时不会按时间补充，阻塞等待 ctx 结束，期间按 idleRecheck 间隔重新检查
+func (tb *TokenBucket) WaitN(ctx context.Context, n int) error {
+	start := tb.now()
+	defer func() {
+		tb.recordDelay(tb.now().Sub(start))
+	}()
+
+	for {
+		if tb.TryConsume(n) {
+			return nil
+		}
+
+		wait, idle, ok := tb.retryAfter(n)
+		if !ok {
+			return ErrExceedsCapacity
+		}
+
+		// 速率为 0 时只是定期重新检查，不需要忙等
+		if err := tb.sleep(ctx, wait, !idle); err != nil {
+			return err
+		}
+		// 令牌可能已被其他调用者抢先消费，重新尝试
+	}
+}
+
+// sleep 等待 d，ctx 结束时返回 ctx.Err()
+//
+// 定时器的精度受平台限制：Windows 默认的时钟分辨率约 15ms，每秒数百个令牌的限流器
+// 单纯依赖定时器时，每次等待都会多出最多一个时钟周期，实际速率明显低于配置。
+// 设置了 SpinWait 时，最后 SpinWait 时间改为忙等，用 CPU 换取节奏精度：
+// 忙等期间调用方所在的 goroutine 持续占用一个 P，只应在确实需要高精度的高速率限流器上启用，
+// SpinWait 取略大于平台时钟分辨率的值即可（如 Windows 上取 16ms）。
+// 使用支持定时器的时间来源（如 ManualClock）或 spin 为 false 时不忙等
+func (tb *TokenBucket) sleep(ctx context.Context, d time.Duration, spin bool) error {
+	var busy time.Duration
+	if spin {
+		busy = tb.spinFor(d)
+	}
+	deadline := tb.now().Add(d)
+
+	if d > busy {
+		select {
+		case <-tb.after(d - busy):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	for busy > 0 && tb.now().Before(deadline) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		runtime.Gosched()
+	}
+	return nil
+}
+
+// spinFor 返回等待 d 时需要忙等的时间
+func (tb *TokenBucket) spinFor(d time.Duration) time.Duration {
+	if tb.SpinWait <= 0 {
+		return 0
+	}
+	if _, ok := tb.clock.(timerClock); ok {
+		return 0
+	}
+	return min(d, tb.SpinWait)
+}
+
+// ConsumeAsync 异步消费 n 个令牌，返回的 channel 在令牌可用时收到 true
+// 调用时立即按调用顺序预留令牌（令牌数可以暂时为负），因此多个 ConsumeAsync 按调用顺序依次完成，
+// 预留的令牌不会被之后的 TryConsume 抢走；预留后无法取消。
+// n 超过桶容量，或速率为 0 且令牌不足时，channel 立即收到 false 且不消费令牌
+func (tb *TokenBucket) ConsumeAsync(n int) <-chan bool {
+	ch := make(chan bool, 1)
+	wait, ok := tb.reserve(n)
+	switch {
+	case !ok:
+		ch <- false
+	case wait == 0:
+		ch <- true
+	default:
+		go func() {
+			<-tb.after(wait)
+			ch <- true
+		}()
+	}
+	return ch
+}
+
+// reserve 预留 n 个令牌，返回预留的令牌全部补充到位还需等待的时间
+func (tb *TokenBucket) reserve(n int) (time.Duration, bool) {
+	tb.lock()
+	defer tb.unlock()
+
+	if n > tb.capacity {
+		return 0, false
+	}
+	tb.refill()
+	per := tb.tokenInterval()
+	if tb.tokens < n && per <= 0 {
+		return 0, false
+	}
+
+	tb.tokens -= n
+	tb.consumed += int64(n)
+	tb.allowed++
+	tb.notifyConsume(n)
+	if tb.tokens >= 0 {
+		return 0, true
+	}
+
+	// 欠下的令牌按速率补充，扣除自上次填充以来已累积的部分
+	wait := time.Duration(-tb.tokens)*per - tb.now().Sub(tb.lastRefill)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
+
+// after 使用时间来源的定时器等待 d，时间来源不支持定时器时使用系统定时器
+func (tb *TokenBucket) after(d time.Duration) <-chan time.Time {
+	if c, ok := tb.clock.(timerClock); ok {
+		return c.After(d)
+	}
+	return time.After(d)
+}
+
+// recordDelay 将一次等待时间记入直方图
+func (tb *TokenBucket) recordDelay(d time.Duration) {
+	tb.lock()
+	defer tb.unlock()
+
+	if tb.delays == nil {
+		tb.delays = make([]int64, len(DelayBounds)+1)
+	}
+	i := 0
+	for i < len(DelayBounds) && d > DelayBounds[i] {
+		i++
+	}
+	tb.delays[i]++
+}
+
+// DelayHistogram 返回 Wait/WaitN 阻塞时间的分布
+// 大部分等待落在高区间时说明速率偏低，可据此调整 rate
+func (tb *TokenBucket) DelayHistogram() DelayHistogram {
+	tb.lock()
+	defer tb.unlock()
+
+	h := DelayHistogram{
+		Bounds: append([]time.Duration(nil), DelayBounds...),
+		Counts: make([]int64, len(DelayBounds)+1),
+	}
+	copy(h.Counts, tb.delays)
+	return h
+}
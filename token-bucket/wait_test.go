@@ -0,0 +1,210 @@
+package tokenbucket
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// waitTimers 等待 clock 上有 n 个尚未到期的定时器
+func waitTimers(t *testing.T, clock *ManualClock, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for clock.pendingTimers() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待 %d 个定时器超时", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestWaitDelayHistogram 测试已知时长的等待落入对应的直方图区间
+func TestWaitDelayHistogram(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	tb := NewTokenBucketWithClock(10, 10, clock) // 每 100ms 一个令牌
+	tb.Drain()
+
+	// waitFor 让 WaitN(n) 阻塞 d 后返回
+	waitFor := func(n int, d time.Duration) {
+		done := make(chan error, 1)
+		go func() {
+			done <- tb.WaitN(context.Background(), n)
+		}()
+		waitTimers(t, clock, 1)
+		clock.Advance(d)
+		if err := <-done; err != nil {
+			t.Fatalf("WaitN(%d) 应在 %v 后成功, 实际: %v", n, d, err)
+		}
+	}
+
+	waitFor(1, 100*time.Millisecond)
+	waitFor(5, 500*time.Millisecond)
+	waitFor(2, 200*time.Millisecond)
+
+	// 令牌充足时不阻塞
+	clock.Advance(time.Second)
+	if err := tb.Wait(context.Background()); err != nil {
+		t.Fatalf("令牌充足时 Wait 应立即成功, 实际: %v", err)
+	}
+
+	h := tb.DelayHistogram()
+	want := map[time.Duration]int64{
+		time.Millisecond:       1, // 0
+		100 * time.Millisecond: 1, // 100ms
+		500 * time.Millisecond: 2, // 200ms, 500ms
+	}
+	for i, bound := range h.Bounds {
+		if h.Counts[i] != want[bound] {
+			t.Errorf("不超过 %v 的区间应有 %d 次, 实际: %d", bound, want[bound], h.Counts[i])
+		}
+	}
+	if h.Counts[len(h.Bounds)] != 0 {
+		t.Errorf("超过全部上界的区间应为 0, 实际: %d", h.Counts[len(h.Bounds)])
+	}
+}
+
+// TestWaitErrors 测试超过容量和 ctx 结束时的错误
+func TestWaitErrors(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	tb := NewTokenBucketWithClock(2, 1, clock)
+
+	if err := tb.WaitN(context.Background(), 3); !errors.Is(err, ErrExceedsCapacity) {
+		t.Errorf("超过容量时应返回 ErrExceedsCapacity, 实际: %v", err)
+	}
+
+	tb.Drain()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- tb.Wait(ctx)
+	}()
+	waitTimers(t, clock, 1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("ctx 结束时应返回 context.Canceled, 实际: %v", err)
+	}
+}
+
+// TestConsumeAsync 测试多个异步消费按调用顺序依次完成
+func TestConsumeAsync(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	tb := NewTokenBucketWithClock(5, 10, clock) // 每 100ms 一个令牌
+	tb.Drain()
+
+	if ok := <-tb.ConsumeAsync(6); ok {
+		t.Error("超过容量的消费应收到 false")
+	}
+
+	// 分别在 100ms、300ms、400ms 后完成
+	chans := []<-chan bool{tb.ConsumeAsync(1), tb.ConsumeAsync(2), tb.ConsumeAsync(1)}
+	if tb.TryConsume(1) {
+		t.Error("预留的令牌不应被之后的 TryConsume 抢走")
+	}
+
+	steps := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 100 * time.Millisecond}
+	for i, step := range steps {
+		waitTimers(t, clock, len(chans)-i)
+		clock.Advance(step)
+		select {
+		case ok := <-chans[i]:
+			if !ok {
+				t.Errorf("第 %d 个异步消费应收到 true", i+1)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("第 %d 个异步消费应在推进 %v 后完成", i+1, step)
+		}
+		for j := i + 1; j < len(chans); j++ {
+			select {
+			case <-chans[j]:
+				t.Errorf("第 %d 个异步消费不应早于第 %d 个完成", j+1, i+1)
+			default:
+			}
+		}
+	}
+
+	// 令牌充足时立即完成
+	clock.Advance(time.Second)
+	if ok := <-tb.ConsumeAsync(2); !ok {
+		t.Error("令牌充足时应立即收到 true")
+	}
+}
+
+// TestWaitSpin 测试 SpinWait 覆盖整个等待时间时，Wait 忙等到令牌到达，且 ctx 结束时及时返回
+func TestWaitSpin(t *testing.T) {
+	tb := NewTokenBucket(1, 100)
+	tb.SpinWait = time.Second
+	tb.TryConsume(1)
+
+	start := time.Now()
+	if err := tb.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait 应成功, 实际: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 9*time.Millisecond {
+		t.Errorf("令牌间隔 10ms, 实际等待 %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := tb.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("忙等期间 ctx 结束应返回 DeadlineExceeded, 实际: %v", err)
+	}
+}
+
+// TestWaitSpinPacing 测试在时钟分辨率较粗的 Windows 上，开启 SpinWait 后高速率限流器的节奏精度
+func TestWaitSpinPacing(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("只在时钟分辨率较粗的 Windows 上测量")
+	}
+
+	const rate, n = 500, 200
+	tb := NewTokenBucket(1, rate)
+	tb.SpinWait = 16 * time.Millisecond
+	tb.TryConsume(1)
+
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		if err := tb.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	elapsed := time.Since(start)
+	want := n * time.Second / rate
+	if elapsed < want*9/10 || elapsed > want*11/10 {
+		t.Errorf("%d 个令牌期望耗时约 %v, 实际: %v", n, want, elapsed)
+	}
+}
+
+// TestWaitZeroRate 测试速率为 0 时等待者阻塞等待 ctx 结束而不是空转, 归还的令牌仍能被等到
+func TestWaitZeroRate(t *testing.T) {
+	tb := NewTokenBucket(1, 0)
+	tb.TryConsume(1)
+
+	if wait := tb.TimeUntilAvailable(1); wait != -1 {
+		t.Errorf("速率为 0 且令牌不足时期望 -1, 实际: %v", wait)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := tb.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("期望 DeadlineExceeded, 实际: %v", err)
+	}
+	if tb.TryConsumeContext(ctx, 1) {
+		t.Error("ctx 已结束, TryConsumeContext 应返回 false")
+	}
+	// 空转时被拒绝的次数会非常多
+	if _, rejected := tb.Counts(); rejected > 10 {
+		t.Errorf("等待期间不应反复尝试消费, 被拒绝 %d 次", rejected)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		tb.Refund(1)
+	}()
+	ctx2, cancel2 := context.WithTimeout(context.Background(), time.Second)
+	defer cancel2()
+	if !tb.TryConsumeContext(ctx2, 1) {
+		t.Error("归还令牌后等待者应获得令牌")
+	}
+}
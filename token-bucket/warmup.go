@@ -0,0 +1,126 @@
+package tokenbucket
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// warmupColdFactor 冷启动时速率为配置速率的 1/warmupColdFactor（与 Guava 的 SmoothWarmingUp 相同）
+const warmupColdFactor = 3
+
+// WarmupTokenBucket 带预热的令牌桶
+// 冷启动或空闲之后，令牌生成速率从 rate/3 开始，在 warmup 时间内线性提高到 rate，
+// 避免刚启动、缓存还是冷的后端立刻承受全速流量。
+//
+// 预热语义：
+//   - 创建时处于冷状态，桶中只有 capacity/3 个令牌
+//   - 连续 warmup 时间没有成功消费视为空闲，下一次请求时重新进入冷状态：
+//     空闲期间累积的令牌最多保留 capacity/3 个，并重新开始预热
+//   - 持续有流量时速率保持在 rate，不会再次预热
+//   - warmup <= 0 表示不预热：创建时满桶，速率始终为 rate，空闲后也不会进入冷状态
+type WarmupTokenBucket struct {
+	mu        sync.Mutex
+	capacity  int
+	rate      float64       // 预热完成后的速率（每秒）
+	coldRate  float64       // 冷状态的速率（每秒）
+	warmup    time.Duration // 预热时长，也是判定空闲的时长
+	tokens    float64       // 当前令牌数，保留小数部分
+	last      time.Time     // 上次补充令牌的时间
+	warmStart time.Time     // 本轮预热开始的时间
+	lastUse   time.Time     // 上次成功消费的时间
+	clock     Clock
+}
+
+// NewWarmupTokenBucket 创建一个带预热的令牌桶
+// capacity: 桶的容量
+// rate: 预热完成后的令牌生成速率（每秒）
+// warmup: 从冷状态速率线性提高到 rate 所需的时间
+func NewWarmupTokenBucket(capacity, rate int, warmup time.Duration) *WarmupTokenBucket {
+	return NewWarmupTokenBucketWithClock(capacity, rate, warmup, realClock{})
+}
+
+// NewWarmupTokenBucketWithClock 使用指定的时间来源创建带预热的令牌桶
+func NewWarmupTokenBucketWithClock(capacity, rate int, warmup time.Duration, clock Clock) *WarmupTokenBucket {
+	now := clock.Now()
+	tokens := coldTokens(capacity)
+	if warmup <= 0 {
+		tokens = float64(capacity)
+	}
+	return &WarmupTokenBucket{
+		capacity:  capacity,
+		rate:      float64(rate),
+		coldRate:  float64(rate) / warmupColdFactor,
+		warmup:    warmup,
+		tokens:    tokens,
+		last:      now,
+		warmStart: now,
+		lastUse:   now,
+		clock:     clock,
+	}
+}
+
+// coldTokens 冷状态下最多保留的令牌数
+func coldTokens(capacity int) float64 {
+	return math.Ceil(float64(capacity) / warmupColdFactor)
+}
+
+// TryConsume 尝试消费令牌
+func (wb *WarmupTokenBucket) TryConsume(count int) bool {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	now := wb.clock.Now()
+	wb.refill(now)
+	if wb.tokens >= float64(count) {
+		wb.tokens -= float64(count)
+		wb.lastUse = now
+		return true
+	}
+	return false
+}
+
+// CurrentRate 返回当前的令牌生成速率（每秒）
+func (wb *WarmupTokenBucket) CurrentRate() float64 {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	now := wb.clock.Now()
+	wb.refill(now)
+	return wb.rateAt(now.Sub(wb.warmStart))
+}
+
+// refill 按预热曲线补充从 last 到 now 的令牌，空闲过久时重新进入冷状态
+func (wb *WarmupTokenBucket) refill(now time.Time) {
+	elapsed := wb.generated(now.Sub(wb.warmStart)) - wb.generated(wb.last.Sub(wb.warmStart))
+	wb.tokens = math.Min(float64(wb.capacity), wb.tokens+elapsed)
+	wb.last = now
+
+	if wb.warmup > 0 && now.Sub(wb.lastUse) >= wb.warmup {
+		wb.tokens = math.Min(wb.tokens, coldTokens(wb.capacity))
+		wb.warmStart = now
+		wb.lastUse = now
+	}
+}
+
+// rateAt 返回预热开始 d 之后的速率
+func (wb *WarmupTokenBucket) rateAt(d time.Duration) float64 {
+	if d >= wb.warmup || wb.warmup <= 0 {
+		return wb.rate
+	}
+	return wb.coldRate + (wb.rate-wb.coldRate)*d.Seconds()/wb.warmup.Seconds()
+}
+
+// generated 返回预热开始后 d 时间内累计生成的令牌数，即速率曲线下的面积
+func (wb *WarmupTokenBucket) generated(d time.Duration) float64 {
+	x := d.Seconds()
+	w := wb.warmup.Seconds()
+	slope := wb.rate - wb.coldRate
+	if w <= 0 {
+		return wb.rate * x
+	}
+	if x <= w {
+		return wb.coldRate*x + slope*x*x/(2*w)
+	}
+	return wb.coldRate*w + slope*w/2 + wb.rate*(x-w)
+}
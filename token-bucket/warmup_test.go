@@ -0,0 +1,78 @@
+package tokenbucket
+
+import (
+	"testing"
+	"time"
+)
+
+// TestWarmupTokenBucket 测试冷启动时速率受限, 预热结束后达到配置速率
+func TestWarmupTokenBucket(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	wb := NewWarmupTokenBucketWithClock(10, 30, 10*time.Second, clock)
+
+	// 取走冷状态的初始令牌
+	for wb.TryConsume(1) {
+	}
+
+	// 持续消费 11 秒, 分别统计第 1 秒和第 11 秒放行的数量
+	first, last := 0, 0
+	for step := 1; step <= 1100; step++ {
+		clock.Advance(10 * time.Millisecond)
+		for wb.TryConsume(1) {
+			switch {
+			case step <= 100:
+				first++
+			case step > 1000:
+				last++
+			}
+		}
+	}
+	t.Logf("第 1 秒放行: %d, 第 11 秒放行: %d", first, last)
+
+	// 第 1 秒速率从 10/s 提高到 12/s, 约 11 个
+	if first < 10 || first > 12 {
+		t.Errorf("冷启动第 1 秒应放行约 11 个, 实际: %d", first)
+	}
+	if last < 29 || last > 31 {
+		t.Errorf("预热结束后应放行约 30 个, 实际: %d", last)
+	}
+	if rate := wb.CurrentRate(); rate != 30 {
+		t.Errorf("预热结束后速率应为 30, 实际: %.2f", rate)
+	}
+}
+
+// TestWarmupIdleReset 测试空闲后重新进入冷状态
+func TestWarmupIdleReset(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	wb := NewWarmupTokenBucketWithClock(30, 30, 10*time.Second, clock)
+
+	clock.Advance(time.Minute)
+	consumed := 0
+	for wb.TryConsume(1) {
+		consumed++
+	}
+	if consumed != 10 {
+		t.Errorf("空闲后最多保留 capacity/3 = 10 个令牌, 实际: %d", consumed)
+	}
+	if rate := wb.CurrentRate(); rate != 10 {
+		t.Errorf("空闲后应回到冷状态速率 10, 实际: %.2f", rate)
+	}
+}
+
+// TestWarmupZero 测试 warmup 为 0 时不预热：满桶启动，空闲后也能攒满容量
+func TestWarmupZero(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	wb := NewWarmupTokenBucketWithClock(9, 3, 0, clock)
+
+	if !wb.TryConsume(9) {
+		t.Fatal("不预热时应满桶启动")
+	}
+	if got := wb.CurrentRate(); got != 3 {
+		t.Errorf("不预热时速率应为 3, 实际: %v", got)
+	}
+
+	clock.Advance(10 * time.Second)
+	if !wb.TryConsume(9) {
+		t.Error("空闲后令牌应补满容量, 不应被限制在 capacity/3")
+	}
+}